@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// BackupSuffix is appended to the boot configuration files backed up before they are changed
+	BackupSuffix = ".kube-apparmor-manager.bak"
+
+	grubFile            = "/etc/default/grub"
+	grubCmdlineKey      = "GRUB_CMDLINE_LINUX_DEFAULT"
+	grubCmdlineLinuxKey = "GRUB_CMDLINE_LINUX"
+	kernelCmdlineFile   = "/etc/kernel/cmdline"
+
+	apparmorLSM = "apparmor"
+)
+
+var (
+	KernelRelease = `uname -r`
+
+	ActiveLSMs = `cat /sys/kernel/security/lsm`
+
+	// exclusiveLSMs can't be stacked with AppArmor, the first one of the lsm= list wins
+	exclusiveLSMs = []string{"selinux", "smack", "tomoyo"}
+
+	// grubCmdlineRegexp matches a command line assignment of /etc/default/grub, the value is double quoted,
+	// single quoted or bare, the rest of the line such as a comment is kept
+	grubCmdlineRegexp = regexp.MustCompile(`^(\s*(` + grubCmdlineLinuxKey + `|` + grubCmdlineKey + `)=)(?:"([^"]*)"|'([^']*)'|([^\s"'#]*))(.*)$`)
+
+	kernelCmdlines = map[string]KernelCmdline{
+		BootloaderGRUB: {
+			File: grubFile,
+			Read: `cat ` + grubFile,
+			Edit: editGrub,
+			Update: []string{
+				`sh -c 'if command -v update-grub >/dev/null 2>&1; then update-grub; else grub2-mkconfig -o /boot/grub2/grub.cfg; fi'`,
+			},
+		},
+		BootloaderSystemdBoot: {
+			File: kernelCmdlineFile,
+			// kernel-install falls back to the running command line when the file doesn't exist
+			Read: `sh -c 'cat ` + kernelCmdlineFile + ` 2>/dev/null || cat /proc/cmdline'`,
+			Edit: editKernelCmdline,
+			Update: []string{
+				`sh -c 'kernel-install add "$(uname -r)" "/boot/vmlinuz-$(uname -r)"'`,
+			},
+		},
+	}
+)
+
+// KernelCmdline describes how the kernel command line is configured for a bootloader
+type KernelCmdline struct {
+	// File is the configuration file containing the command line
+	File string
+	// Read prints the current configuration
+	Read string
+	// Edit returns the configuration with AppArmor enabled and whether it changed
+	Edit func(content, kernelRelease, activeLSMs string) (string, bool, error)
+	// Update regenerates the boot configuration once File changed
+	Update []string
+}
+
+// KernelCmdlineFor returns how the kernel command line is configured for a bootloader, nil if it is unknown
+func KernelCmdlineFor(bootloader string) *KernelCmdline {
+	if k, ok := kernelCmdlines[bootloader]; ok {
+		return &k
+	}
+
+	return nil
+}
+
+// ConfigureCommands returns the commands backing up the configuration file (only once, so the original is kept),
+// writing the new content and updating the boot configuration
+func (k *KernelCmdline) ConfigureCommands(content string) []string {
+	commands := []string{
+		fmt.Sprintf(`sh -c '[ -e %[1]s%[2]s ] || [ ! -e %[1]s ] || cp -p %[1]s %[1]s%[2]s'`, k.File, BackupSuffix),
+		WriteFileCommand(k.File, content),
+	}
+
+	return append(commands, k.Update...)
+}
+
+// BackupExistsCommand returns a command printing yes if the configuration file was backed up
+func (k *KernelCmdline) BackupExistsCommand() string {
+	return fmt.Sprintf(`sh -c '[ -e %s%s ] && echo yes || true'`, k.File, BackupSuffix)
+}
+
+// RevertCommands returns the commands restoring the configuration file from the backup and updating the boot configuration
+func (k *KernelCmdline) RevertCommands() []string {
+	commands := []string{
+		fmt.Sprintf(`mv %[1]s%[2]s %[1]s`, k.File, BackupSuffix),
+	}
+
+	return append(commands, k.Update...)
+}
+
+// WriteFileCommand returns a command writing content to a file, the content is base64 encoded so that it doesn't need quoting
+func WriteFileCommand(file, content string) string {
+	return fmt.Sprintf(`sh -c 'echo %s | base64 -d > %s'`, base64.StdEncoding.EncodeToString([]byte(content)), file)
+}
+
+// grubCmdline is a command line assignment of /etc/default/grub
+type grubCmdline struct {
+	line   int
+	prefix string
+	key    string
+	quote  string
+	value  string
+	rest   string
+}
+
+// parseGrubCmdline returns the command line assigned by a line, nil if it assigns none
+func parseGrubCmdline(i int, line string) *grubCmdline {
+	m := grubCmdlineRegexp.FindStringSubmatch(line)
+
+	if m == nil {
+		return nil
+	}
+
+	c := &grubCmdline{line: i, prefix: m[1], key: m[2], rest: m[6]}
+
+	switch {
+	case strings.HasPrefix(line[len(m[1]):], `"`):
+		c.quote, c.value = `"`, m[3]
+	case strings.HasPrefix(line[len(m[1]):], `'`):
+		c.quote, c.value = `'`, m[4]
+	default:
+		c.quote, c.value = `"`, m[5]
+	}
+
+	return c
+}
+
+// setsLSMs checks whether a command line selects the LSMs
+func (c *grubCmdline) setsLSMs() bool {
+	for _, arg := range strings.Fields(c.value) {
+		if strings.HasPrefix(arg, "lsm=") || strings.HasPrefix(arg, "security=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// editGrub enables AppArmor in GRUB_CMDLINE_LINUX_DEFAULT, or in GRUB_CMDLINE_LINUX when the LSMs are selected
+// there only, since GRUB_CMDLINE_LINUX_DEFAULT is appended to it and its lsm= would win
+func editGrub(content, kernelRelease, activeLSMs string) (string, bool, error) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	cmdlines := map[string][]*grubCmdline{}
+
+	for i, line := range lines {
+		if c := parseGrubCmdline(i, line); c != nil {
+			cmdlines[c.key] = append(cmdlines[c.key], c)
+		}
+	}
+
+	key := grubCmdlineKey
+
+	if setLSMs(cmdlines[grubCmdlineLinuxKey]) && !setLSMs(cmdlines[grubCmdlineKey]) {
+		key = grubCmdlineLinuxKey
+	}
+
+	changed := false
+
+	for _, c := range cmdlines[key] {
+		cmdline, lineChanged, err := EnableAppArmorArgs(c.value, kernelRelease, activeLSMs)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		if lineChanged {
+			lines[c.line] = c.prefix + c.quote + cmdline + c.quote + c.rest
+			changed = true
+		}
+	}
+
+	if len(cmdlines[key]) == 0 {
+		cmdline, _, err := EnableAppArmorArgs("", kernelRelease, activeLSMs)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		lines = append(lines, fmt.Sprintf(`%s="%s"`, grubCmdlineKey, cmdline))
+		changed = true
+	}
+
+	return strings.Join(lines, "\n") + "\n", changed, nil
+}
+
+// setLSMs checks whether one of the command lines selects the LSMs
+func setLSMs(cmdlines []*grubCmdline) bool {
+	for _, c := range cmdlines {
+		if c.setsLSMs() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func editKernelCmdline(content, kernelRelease, activeLSMs string) (string, bool, error) {
+	args := []string{}
+
+	// arguments set by the bootloader itself when read from /proc/cmdline
+	for _, arg := range strings.Fields(content) {
+		if !strings.HasPrefix(arg, "BOOT_IMAGE=") && !strings.HasPrefix(arg, "initrd=") {
+			args = append(args, arg)
+		}
+	}
+
+	cmdline, changed, err := EnableAppArmorArgs(strings.Join(args, " "), kernelRelease, activeLSMs)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	if strings.TrimSpace(content) != strings.Join(args, " ") {
+		changed = true
+	}
+
+	return cmdline + "\n", changed, nil
+}
+
+// EnableAppArmorArgs enables AppArmor on a kernel command line. apparmor is added to the lsm= list, which is
+// initialized with the active LSMs (content of /sys/kernel/security/lsm) if missing, so that the other LSMs stay enabled.
+// Kernels older than 5.1 don't support lsm=, security=apparmor is used instead. Duplicated arguments are removed.
+// It returns the new command line and whether it changed.
+func EnableAppArmorArgs(cmdline, kernelRelease, activeLSMs string) (string, bool, error) {
+	args := []string{}
+	seen := map[string]bool{}
+
+	for _, arg := range strings.Fields(cmdline) {
+		if seen[arg] {
+			continue
+		}
+
+		seen[arg] = true
+		args = append(args, arg)
+	}
+
+	lsmIndex := -1
+	securityIndex := -1
+
+	for i, arg := range args {
+		switch {
+		case arg == "apparmor=0":
+			args[i] = "apparmor=1"
+		case strings.HasPrefix(arg, "lsm="):
+			lsmIndex = i
+		case strings.HasPrefix(arg, "security="):
+			securityIndex = i
+		}
+	}
+
+	if !supportsLSMList(kernelRelease) {
+		switch {
+		case securityIndex == -1:
+			args = append(args, "security=apparmor")
+		case args[securityIndex] != "security=apparmor":
+			return "", false, fmt.Errorf("kernel %s doesn't support lsm=, AppArmor conflicts with %s", kernelRelease, args[securityIndex])
+		}
+	} else if lsmIndex >= 0 {
+		args[lsmIndex] = "lsm=" + addAppArmorLSM(strings.TrimPrefix(args[lsmIndex], "lsm="))
+	} else {
+		lsms := strings.TrimSpace(activeLSMs)
+
+		if lsms == "" {
+			return "", false, fmt.Errorf("failed to read the active LSMs")
+		}
+
+		args = append(args, "lsm="+addAppArmorLSM(lsms))
+	}
+
+	result := strings.Join(args, " ")
+
+	return result, result != strings.Join(strings.Fields(cmdline), " "), nil
+}
+
+// addAppArmorLSM adds apparmor to a comma separated LSM list, ahead of the LSMs it can't be stacked with
+func addAppArmorLSM(list string) string {
+	lsms := []string{}
+
+	for _, lsm := range strings.Split(list, ",") {
+		if lsm = strings.TrimSpace(lsm); lsm != "" {
+			lsms = append(lsms, lsm)
+		}
+	}
+
+	for _, lsm := range lsms {
+		if lsm == apparmorLSM {
+			return strings.Join(lsms, ",")
+		}
+	}
+
+	for i, lsm := range lsms {
+		for _, exclusive := range exclusiveLSMs {
+			if lsm == exclusive {
+				lsms = append(lsms[:i], append([]string{apparmorLSM}, lsms[i:]...)...)
+				return strings.Join(lsms, ",")
+			}
+		}
+	}
+
+	return strings.Join(append(lsms, apparmorLSM), ",")
+}
+
+// supportsLSMList checks whether the kernel supports the lsm= argument, which was added in 5.1
+func supportsLSMList(kernelRelease string) bool {
+	parts := strings.SplitN(kernelRelease, ".", 3)
+
+	if len(parts) < 2 {
+		return true
+	}
+
+	major, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		// assume a recent kernel if the release can't be parsed
+		return true
+	}
+
+	minor, _ := strconv.Atoi(leadingDigits(parts[1]))
+
+	return major > 5 || (major == 5 && minor >= 1)
+}
+
+func leadingDigits(s string) string {
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return s[:i]
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"testing"
+)
+
+const testLSMs = "lockdown,capability,yama"
+
+func TestEditGrub(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		kernelRelease string
+		want          string
+		changed       bool
+		err           string
+	}{
+		{
+			name:          "double quoted value with a trailing comment",
+			src:           "GRUB_DEFAULT=0\nGRUB_CMDLINE_LINUX_DEFAULT=\"quiet splash\" # set by the installer\nGRUB_CMDLINE_LINUX=\"\"\n",
+			kernelRelease: "5.15.0-91-generic",
+			want:          "GRUB_DEFAULT=0\nGRUB_CMDLINE_LINUX_DEFAULT=\"quiet splash lsm=lockdown,capability,yama,apparmor\" # set by the installer\nGRUB_CMDLINE_LINUX=\"\"\n",
+			changed:       true,
+		},
+		{
+			name:          "single quoted value with a trailing comment",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT='quiet' # comment\n",
+			kernelRelease: "6.1.0",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT='quiet lsm=lockdown,capability,yama,apparmor' # comment\n",
+			changed:       true,
+		},
+		{
+			name:          "bare value with a trailing comment is quoted",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=quiet # comment\n",
+			kernelRelease: "6.1.0",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet lsm=lockdown,capability,yama,apparmor\" # comment\n",
+			changed:       true,
+		},
+		{
+			name:          "lsm= in GRUB_CMDLINE_LINUX only",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet\"\nGRUB_CMDLINE_LINUX=\"console=ttyS0 lsm=selinux\"\n",
+			kernelRelease: "5.15.0",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet\"\nGRUB_CMDLINE_LINUX=\"console=ttyS0 lsm=apparmor,selinux\"\n",
+			changed:       true,
+		},
+		{
+			name:          "lsm= in both lines edits GRUB_CMDLINE_LINUX_DEFAULT",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=\"lsm=yama\"\nGRUB_CMDLINE_LINUX=\"lsm=selinux\"\n",
+			kernelRelease: "5.15.0",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT=\"lsm=yama,apparmor\"\nGRUB_CMDLINE_LINUX=\"lsm=selinux\"\n",
+			changed:       true,
+		},
+		{
+			name:          "kernel before 5.1 uses security=",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet\"\n",
+			kernelRelease: "4.19.0-25-amd64",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet security=apparmor\"\n",
+			changed:       true,
+		},
+		{
+			name:          "missing line appended",
+			src:           "GRUB_DEFAULT=0\n",
+			kernelRelease: "5.15.0",
+			want:          "GRUB_DEFAULT=0\nGRUB_CMDLINE_LINUX_DEFAULT=\"lsm=lockdown,capability,yama,apparmor\"\n",
+			changed:       true,
+		},
+		{
+			name:          "commented out line ignored",
+			src:           "#GRUB_CMDLINE_LINUX_DEFAULT=\"quiet\"\n",
+			kernelRelease: "5.15.0",
+			want:          "#GRUB_CMDLINE_LINUX_DEFAULT=\"quiet\"\nGRUB_CMDLINE_LINUX_DEFAULT=\"lsm=lockdown,capability,yama,apparmor\"\n",
+			changed:       true,
+		},
+		{
+			name:          "AppArmor already enabled",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet lsm=landlock,apparmor\"\n",
+			kernelRelease: "5.15.0",
+			want:          "GRUB_CMDLINE_LINUX_DEFAULT=\"quiet lsm=landlock,apparmor\"\n",
+		},
+		{
+			name:          "kernel before 5.1 with another security=",
+			src:           "GRUB_CMDLINE_LINUX_DEFAULT=\"security=selinux\"\n",
+			kernelRelease: "4.19.0",
+			err:           "kernel 4.19.0 doesn't support lsm=, AppArmor conflicts with security=selinux",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := editGrub(tt.src, tt.kernelRelease, testLSMs)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("expected %q (changed %v), got %q (changed %v)", tt.want, tt.changed, got, changed)
+			}
+
+			again, changed, err := editGrub(got, tt.kernelRelease, testLSMs)
+
+			if err != nil || again != got || changed {
+				t.Errorf("editing is not idempotent: %q (changed %v), %v", again, changed, err)
+			}
+		})
+	}
+}
+
+func TestEnableAppArmorArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		cmdline       string
+		kernelRelease string
+		activeLSMs    string
+		want          string
+		changed       bool
+		err           string
+	}{
+		{
+			name:          "lsm= initialized with the active LSMs",
+			cmdline:       "ro quiet",
+			kernelRelease: "5.4.0",
+			activeLSMs:    testLSMs + "\n",
+			want:          "ro quiet lsm=lockdown,capability,yama,apparmor",
+			changed:       true,
+		},
+		{
+			name:          "existing lsm= list extended",
+			cmdline:       "ro lsm=yama,bpf",
+			kernelRelease: "5.1.0",
+			activeLSMs:    testLSMs,
+			want:          "ro lsm=yama,bpf,apparmor",
+			changed:       true,
+		},
+		{
+			name:          "kernel before 5.1",
+			cmdline:       "ro",
+			kernelRelease: "5.0.21",
+			activeLSMs:    testLSMs,
+			want:          "ro security=apparmor",
+			changed:       true,
+		},
+		{
+			name:          "kernel before 5.1 with security=apparmor",
+			cmdline:       "ro security=apparmor",
+			kernelRelease: "4.15.0-213-generic",
+			activeLSMs:    testLSMs,
+			want:          "ro security=apparmor",
+		},
+		{
+			name:          "kernel before 5.1 with another security=",
+			cmdline:       "security=selinux",
+			kernelRelease: "4.18.0",
+			activeLSMs:    testLSMs,
+			err:           "kernel 4.18.0 doesn't support lsm=, AppArmor conflicts with security=selinux",
+		},
+		{
+			name:          "apparmor=0 turned on",
+			cmdline:       "apparmor=0 lsm=apparmor",
+			kernelRelease: "6.8.0",
+			activeLSMs:    testLSMs,
+			want:          "apparmor=1 lsm=apparmor",
+			changed:       true,
+		},
+		{
+			name:          "duplicated arguments removed",
+			cmdline:       "quiet quiet lsm=apparmor",
+			kernelRelease: "6.8.0",
+			activeLSMs:    testLSMs,
+			want:          "quiet lsm=apparmor",
+			changed:       true,
+		},
+		{
+			name:          "spacing only is not a change",
+			cmdline:       "  quiet   lsm=apparmor ",
+			kernelRelease: "6.8.0",
+			activeLSMs:    testLSMs,
+			want:          "quiet lsm=apparmor",
+		},
+		{
+			name:          "active LSMs unknown",
+			cmdline:       "quiet",
+			kernelRelease: "6.8.0",
+			activeLSMs:    " \n",
+			err:           "failed to read the active LSMs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := EnableAppArmorArgs(tt.cmdline, tt.kernelRelease, tt.activeLSMs)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("expected %q (changed %v), got %q (changed %v)", tt.want, tt.changed, got, changed)
+			}
+
+			again, changed, err := EnableAppArmorArgs(got, tt.kernelRelease, tt.activeLSMs)
+
+			if err != nil || again != got || changed {
+				t.Errorf("enabling is not idempotent: %q (changed %v), %v", again, changed, err)
+			}
+		})
+	}
+}
+
+func TestAddAppArmorLSM(t *testing.T) {
+	tests := []struct {
+		list string
+		want string
+	}{
+		{list: "lockdown,capability,yama", want: "lockdown,capability,yama,apparmor"},
+		{list: "capability,selinux", want: "capability,apparmor,selinux"},
+		{list: "smack,selinux", want: "apparmor,smack,selinux"},
+		{list: "yama,tomoyo", want: "yama,apparmor,tomoyo"},
+		{list: "apparmor,selinux", want: "apparmor,selinux"},
+		{list: "selinux,apparmor", want: "selinux,apparmor"},
+		{list: " yama, ,bpf ", want: "yama,bpf,apparmor"},
+		{list: "", want: "apparmor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got := addAppArmorLSM(tt.list)
+
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+
+			if again := addAppArmorLSM(got); again != got {
+				t.Errorf("adding is not idempotent: %q", again)
+			}
+		})
+	}
+}
+
+func TestSupportsLSMList(t *testing.T) {
+	tests := []struct {
+		kernelRelease string
+		want          bool
+	}{
+		{kernelRelease: "4.19.0-25-amd64", want: false},
+		{kernelRelease: "5.0.21", want: false},
+		{kernelRelease: "5.1.0", want: true},
+		{kernelRelease: "5.15.0-91-generic", want: true},
+		{kernelRelease: "6.1.0", want: true},
+		{kernelRelease: "3.10.0-1160.el7.x86_64", want: false},
+		{kernelRelease: "5.1-rc1", want: true},
+		{kernelRelease: "5.0-rc1", want: false},
+		{kernelRelease: "unknown", want: true},
+		{kernelRelease: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kernelRelease, func(t *testing.T) {
+			got := supportsLSMList(tt.kernelRelease)
+
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,22 +2,28 @@ package commands
 
 import (
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/sysdiglabs/kube-apparmor-manager/types"
 )
 
+const (
+	// ManagedHeader is the first line of the profiles created on worker nodes, it tells them apart from the other profiles
+	ManagedHeader = "# Managed by kube-apparmor-manager, do not edit"
+
+	// FragmentDir is the directory the profile fragments are deployed to on worker nodes
+	FragmentDir = "/etc/apparmor.d/" + types.FragmentIncludeDir
+
+	// SyncStateFile records the hashes of the profiles and fragments deployed by the last sync on worker nodes
+	SyncStateFile = "/var/lib/kube-apparmor-manager/profiles"
+)
+
 var (
-	AAEnable        = "aa-enabled"
-	InstallAppArmor = []string{
-		`apt update`,
-		`apt install -y apparmor-profiles apparmor-utils`,
-		`sed -i -e '/^GRUB_CMDLINE_LINUX_DEFAULT/s/"$/ apparmor=1 security=apparmor"/' /etc/default/grub`,
-		`update-grub`,
-		`reboot`,
-	}
+	AAEnable = "aa-enabled"
 
 	CreateAppArmorProfileTemplate = []string{
-		`echo '%s' > /tmp/%s`,
 		`mv /tmp/%s /etc/apparmor.d/%s`,
 	}
 
@@ -34,17 +40,55 @@ var (
 	ComplainAppArmorProfileTempalte = []string{
 		`aa-complain /etc/apparmor.d/%s`,
 	}
+
+	// LoadAppArmorProfileTemplate loads a profile in the mode set by its flags, e.g. kill or unconfined
+	LoadAppArmorProfileTemplate = []string{
+		`rm -f /etc/apparmor.d/disable/%[1]s /etc/apparmor.d/force-complain/%[1]s`,
+		`apparmor_parser -r -W /etc/apparmor.d/%[1]s`,
+	}
+
+	ListManagedProfiles = `sh -c 'grep -l -x "` + ManagedHeader + `" /etc/apparmor.d/* 2>/dev/null || true'`
+
+	ListFragments = `sh -c 'ls ` + FragmentDir + ` 2>/dev/null || true'`
+
+	// ListFragmentIncludes prints the fragment includes of the managed profiles deployed on worker nodes
+	ListFragmentIncludes = `sh -c 'grep -l -x "` + ManagedHeader + `" /etc/apparmor.d/* 2>/dev/null | xargs -r grep -h -o "<` + types.FragmentIncludeDir + `/[^>]*>" 2>/dev/null || true'`
+
+	ReadSyncState = `sh -c 'cat ` + SyncStateFile + ` 2>/dev/null || true'`
+
+	RemoveFragmentTemplate = []string{
+		`rm -f ` + FragmentDir + `/%s`,
+	}
+
+	// RemoveManagedFiles removes the fragments and the sync state from worker nodes
+	RemoveManagedFiles = []string{
+		`rm -rf ` + FragmentDir + ` ` + path.Dir(SyncStateFile),
+	}
+
+	RemoveAppArmorProfileTemplate = []string{
+		`sh -c 'if [ -e /etc/apparmor.d/%[1]s ]; then apparmor_parser -R /etc/apparmor.d/%[1]s || true; fi'`,
+		`rm -f /etc/apparmor.d/%[1]s /etc/apparmor.d/disable/%[1]s /etc/apparmor.d/force-complain/%[1]s`,
+	}
 )
 
+// ProfileFile returns the content of the file a profile is deployed to on worker nodes
+func ProfileFile(profile types.AppArmorProfile) string {
+	return ManagedHeader + "\n" + profile.String() + "\n"
+}
+
+// FragmentFile returns the content of the file a profile fragment is deployed to on worker nodes
+func FragmentFile(fragment types.AppArmorProfileFragment) string {
+	return ManagedHeader + "\n" + fragment.String() + "\n"
+}
+
 // CreateProfileCommands returns a list of commands to create AppArmor profiles on worker nodes
 func CreateProfileCommands(profile types.AppArmorProfile) []string {
 	commands := make([]string, 2)
 
-	commands[0] = fmt.Sprintf(CreateAppArmorProfileTemplate[0], profile, profile.Name)
+	// the profile is written encoded, a raw profile text may contain any character
+	commands[0] = WriteFileCommand(path.Join("/tmp", profile.Name), ProfileFile(profile))
 
-	commands[1] = fmt.Sprintf(CreateAppArmorProfileTemplate[1], profile.Name, profile.Name)
-
-	//commands[4] = CreateAppArmorProfileTemplate[4]
+	commands[1] = fmt.Sprintf(CreateAppArmorProfileTemplate[0], profile.Name, profile.Name)
 
 	return commands
 }
@@ -74,3 +118,116 @@ func ComplainProfileCommands(profile types.AppArmorProfile) []string {
 
 	return commands
 }
+
+// LoadProfileCommands returns a list of commands to load a profile in the mode set by its flags on worker nodes
+func LoadProfileCommands(profile types.AppArmorProfile) []string {
+	commands := make([]string, 2)
+
+	commands[0] = fmt.Sprintf(LoadAppArmorProfileTemplate[0], profile.Name)
+
+	commands[1] = fmt.Sprintf(LoadAppArmorProfileTemplate[1], profile.Name)
+
+	return commands
+}
+
+// RemoveProfileCommands returns a list of commands to unload and delete a profile on worker nodes
+func RemoveProfileCommands(name string) []string {
+	commands := make([]string, 2)
+
+	commands[0] = fmt.Sprintf(RemoveAppArmorProfileTemplate[0], name)
+
+	commands[1] = fmt.Sprintf(RemoveAppArmorProfileTemplate[1], name)
+
+	return commands
+}
+
+// CreateFragmentCommands returns a list of commands to create a profile fragment on worker nodes
+func CreateFragmentCommands(fragment types.AppArmorProfileFragment) []string {
+	commands := make([]string, 2)
+
+	commands[0] = `mkdir -p ` + FragmentDir
+
+	commands[1] = WriteFileCommand(path.Join(FragmentDir, fragment.Name), FragmentFile(fragment))
+
+	return commands
+}
+
+// RemoveFragmentCommands returns a list of commands to delete a profile fragment on worker nodes
+func RemoveFragmentCommands(name string) []string {
+	commands := make([]string, 1)
+
+	commands[0] = fmt.Sprintf(RemoveFragmentTemplate[0], name)
+
+	return commands
+}
+
+// WriteSyncStateCommands returns a list of commands to record the hashes of the deployed profiles and fragments
+func WriteSyncStateCommands(state map[string]string) []string {
+	keys := []string{}
+
+	for key := range state {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s %s\n", key, state[key])
+	}
+
+	commands := make([]string, 2)
+
+	commands[0] = `mkdir -p ` + path.Dir(SyncStateFile)
+
+	commands[1] = WriteFileCommand(SyncStateFile, b.String())
+
+	return commands
+}
+
+// ParseSyncState returns the hashes printed by ReadSyncState indexed by profile or fragment key
+func ParseSyncState(stdout string) map[string]string {
+	state := map[string]string{}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) == 2 {
+			state[fields[0]] = fields[1]
+		}
+	}
+
+	return state
+}
+
+// ParseFragments returns the names of the fragments listed by ListFragments
+func ParseFragments(stdout string) []string {
+	return strings.Fields(stdout)
+}
+
+// ParseFragmentIncludes returns the names of the fragments included according to ListFragmentIncludes
+func ParseFragmentIncludes(stdout string) map[string]bool {
+	names := map[string]bool{}
+
+	for _, include := range strings.Fields(stdout) {
+		name := strings.TrimPrefix(strings.TrimSuffix(include, ">"), "<"+types.FragmentIncludeDir+"/")
+
+		if name != "" {
+			names[name] = true
+		}
+	}
+
+	return names
+}
+
+// ParseManagedProfiles returns the names of the profiles listed by ListManagedProfiles
+func ParseManagedProfiles(stdout string) []string {
+	names := []string{}
+
+	for _, file := range strings.Fields(stdout) {
+		names = append(names, path.Base(file))
+	}
+
+	return names
+}
@@ -0,0 +1,26 @@
+package commands
+
+var (
+	// the exit status of the remote commands is not available, the checks are evaluated from their output only,
+	// an empty output is a result as well, e.g. CheckSecurityFS prints nothing when securityfs isn't mounted
+
+	CheckSudo = `sudo -n true`
+
+	CheckLSM = `cat /sys/kernel/security/lsm`
+
+	CheckModuleParameters = `sh -c 'if [ -d /sys/module/apparmor/parameters ]; then for p in enabled mode; do echo "$p=$(cat /sys/module/apparmor/parameters/$p 2>/dev/null)"; done; fi'`
+
+	CheckSecurityFS = `sh -c 'grep -w securityfs /proc/mounts || true'`
+
+	CheckParser = `sh -c 'apparmor_parser --version 2>&1 | head -n 1'`
+
+	CheckUtils = `sh -c 'for c in aa-enforce aa-complain aa-disable aa-status; do command -v $c >/dev/null 2>&1 || echo $c; done'`
+
+	// CheckRuntime prints a line by runtime, containerd is followed by the disable_apparmor setting of its CRI plugin
+	// and CRI-O by its default AppArmor profile, if they report them
+	CheckRuntime = `sh -c 'if command -v docker >/dev/null 2>&1; then echo "docker $(docker info --format "{{.SecurityOptions}}" 2>/dev/null)"; fi; ` +
+		`if command -v containerd >/dev/null 2>&1; then echo "$(containerd --version) $(containerd config dump 2>/dev/null | grep -m 1 -o "disable_apparmor = [a-z]*")"; fi; ` +
+		`if command -v crio >/dev/null 2>&1; then echo "cri-o $(crio --version 2>/dev/null | head -n 1) $(crio config 2>/dev/null | grep -m 1 -o "apparmor_profile = .*")"; fi'`
+
+	CheckFreeSpace = `sh -c 'df -Pk /etc/apparmor.d 2>/dev/null | tail -n 1'`
+)
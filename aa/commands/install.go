@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// Distribution families with an AppArmor installer
+const (
+	FamilyDebian = "debian"
+	FamilySUSE   = "suse"
+	FamilyFedora = "fedora"
+)
+
+// Bootloaders reported by DetectBootloader
+const (
+	BootloaderGRUB        = "grub"
+	BootloaderSystemdBoot = "systemd-boot"
+	BootloaderNone        = "none"
+	// BootloaderImmutable is reported for image based systems (e.g. ostree) where the boot configuration can't be changed
+	BootloaderImmutable = "immutable"
+)
+
+var (
+	DetectOSRelease = `cat /etc/os-release`
+
+	DetectBootloader = `sh -c 'if [ -e /run/ostree-booted ]; then echo immutable; ` +
+		`elif [ -f /etc/default/grub ]; then echo grub; ` +
+		`elif command -v bootctl >/dev/null 2>&1 && bootctl is-installed >/dev/null 2>&1; then echo systemd-boot; ` +
+		`else echo none; fi'`
+
+	// immutableImages are container optimized distributions which ship a read-only image, AppArmor is either built in or not available
+	immutableImages = []string{"cos", "bottlerocket", "flatcar", "talos", "rhcos", "fedora-coreos", "ubuntu-core"}
+
+	installers = []Installer{
+		{
+			Family: FamilyDebian,
+			IDs:    []string{"debian", "ubuntu"},
+			Commands: []string{
+				`apt update`,
+				`apt install -y apparmor apparmor-profiles apparmor-utils`,
+			},
+		},
+		{
+			Family: FamilySUSE,
+			IDs:    []string{"suse", "opensuse", "sles"},
+			Commands: []string{
+				`zypper --non-interactive install apparmor-parser apparmor-utils apparmor-profiles`,
+			},
+		},
+		{
+			Family:    FamilyFedora,
+			IDs:       []string{"fedora", "rhel", "centos"},
+			Available: `dnf -q list apparmor-parser`,
+			Commands: []string{
+				`dnf install -y apparmor-parser apparmor-utils`,
+			},
+		},
+	}
+
+	Reboot = `reboot`
+)
+
+// Installer installs the AppArmor packages on a distribution family
+type Installer struct {
+	Family string
+	// IDs are the os-release ids (or ID_LIKE values) of the family
+	IDs []string
+	// Available is run before installing, an empty output means the AppArmor packages are not available
+	Available string
+	Commands  []string
+}
+
+// UnsupportedError is returned when AppArmor can't be installed on a node
+type UnsupportedError struct {
+	Reason string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Reason
+}
+
+// NewInstaller returns the installer for the distribution of a node
+func NewInstaller(osr types.OSRelease) (*Installer, error) {
+	id := osr.ID
+	if osr.VariantID == "coreos" {
+		id = fmt.Sprintf("%s-coreos", osr.ID)
+	}
+
+	for _, image := range immutableImages {
+		if id == image {
+			return nil, &UnsupportedError{Reason: fmt.Sprintf("%s is an immutable image, AppArmor must be built into the image", osr)}
+		}
+	}
+
+	for _, installer := range installers {
+		if osr.Is(installer.IDs...) {
+			i := installer
+			return &i, nil
+		}
+	}
+
+	return nil, &UnsupportedError{Reason: fmt.Sprintf("no AppArmor installer for %s", osr)}
+}
@@ -0,0 +1,224 @@
+package aa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+const (
+	// minimum free space in /etc/apparmor.d, in KB
+	minFreeSpace  = 1024
+	warnFreeSpace = 10 * 1024
+)
+
+// check is a readiness check run on a node, evaluate turns the command output into a finding
+type check struct {
+	name     string
+	command  string
+	sudo     bool
+	evaluate func(stdout, stderr string) types.Finding
+}
+
+var checks = []check{
+	{
+		name:    "sudo",
+		command: commands.CheckSudo,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stderr != "" {
+				return failed(fmt.Sprintf("passwordless sudo is not available: %s", stderr), "allow the SSH user to run sudo without a password")
+			}
+			return passed("passwordless sudo is available")
+		},
+	},
+	{
+		name:    "kernel LSM",
+		command: commands.CheckLSM,
+		sudo:    true,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout == "" {
+				return failed(fmt.Sprintf("failed to read the active LSMs: %s", stderr), "make sure securityfs is mounted")
+			}
+			for _, lsm := range strings.Split(stdout, ",") {
+				if lsm == "apparmor" {
+					return passed(fmt.Sprintf("active LSMs: %s", stdout))
+				}
+			}
+			return failed(fmt.Sprintf("apparmor is not an active LSM: %s", stdout), "run init to add apparmor to the lsm= kernel argument and restart the node")
+		},
+	},
+	{
+		name:    "module parameters",
+		command: commands.CheckModuleParameters,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout == "" {
+				return failed("the kernel is not built with AppArmor", "use a node image with an AppArmor enabled kernel")
+			}
+			params := parseKeyValues(stdout)
+			if params["enabled"] != "Y" {
+				return failed(fmt.Sprintf("AppArmor is disabled: enabled=%s", params["enabled"]), "remove apparmor=0 from the kernel command line, run init and restart the node")
+			}
+			return passed(fmt.Sprintf("enabled=%s mode=%s", params["enabled"], params["mode"]))
+		},
+	},
+	{
+		name:    "securityfs",
+		command: commands.CheckSecurityFS,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout == "" {
+				return failed("securityfs is not mounted", "mount -t securityfs securityfs /sys/kernel/security")
+			}
+			return passed(strings.Split(stdout, "\n")[0])
+		},
+	},
+	{
+		name:    "apparmor_parser",
+		command: commands.CheckParser,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout == "" || strings.Contains(stdout, "not found") {
+				return failed("apparmor_parser is not installed", "run init to install the AppArmor packages")
+			}
+			return passed(stdout)
+		},
+	},
+	{
+		name:    "AppArmor utils",
+		command: commands.CheckUtils,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout != "" {
+				return failed(fmt.Sprintf("missing: %s", strings.Join(strings.Fields(stdout), ", ")), "run init to install the AppArmor utils package")
+			}
+			return passed("aa-enforce, aa-complain, aa-disable and aa-status are installed")
+		},
+	},
+	{
+		name:    "container runtime",
+		command: commands.CheckRuntime,
+		sudo:    true,
+		evaluate: func(stdout, stderr string) types.Finding {
+			if stdout == "" {
+				return warning("no container runtime found (docker, containerd, cri-o)", "")
+			}
+			unverified := []string{}
+			for _, line := range strings.Split(stdout, "\n") {
+				switch {
+				case strings.HasPrefix(line, "docker ") && !strings.Contains(line, "apparmor"):
+					return warning("docker doesn't report AppArmor in its security options", "restart docker once AppArmor is enabled")
+				case strings.HasPrefix(line, "containerd ") && strings.Contains(line, "disable_apparmor = true"):
+					return failed("containerd disables AppArmor", "set disable_apparmor = false in the CRI plugin configuration of containerd and restart it")
+				case strings.HasPrefix(line, "containerd ") && !strings.Contains(line, "disable_apparmor"):
+					unverified = append(unverified, "containerd")
+				case strings.HasPrefix(line, "cri-o ") && !strings.Contains(line, "apparmor_profile"):
+					unverified = append(unverified, "cri-o")
+				}
+			}
+			message := strings.Replace(stdout, "\n", "; ", -1)
+			if len(unverified) > 0 {
+				return types.Finding{Status: types.FindingUnverified, Message: fmt.Sprintf("%s, AppArmor support of %s is not reported", message, strings.Join(unverified, " and "))}
+			}
+			return passed(message)
+		},
+	},
+	{
+		name:    "free space",
+		command: commands.CheckFreeSpace,
+		evaluate: func(stdout, stderr string) types.Finding {
+			fields := strings.Fields(stdout)
+			if len(fields) < 6 {
+				return failed("/etc/apparmor.d doesn't exist", "run init to install the AppArmor packages")
+			}
+			free, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return warning(fmt.Sprintf("failed to parse the free space: %s", stdout), "")
+			}
+			message := fmt.Sprintf("%d MB free in /etc/apparmor.d", free/1024)
+			switch {
+			case free < minFreeSpace:
+				return failed(message, fmt.Sprintf("free up space on %s", fields[5]))
+			case free < warnFreeSpace:
+				return warning(message, fmt.Sprintf("free up space on %s", fields[5]))
+			}
+			return passed(message)
+		},
+	},
+}
+
+// Doctor runs the AppArmor readiness checks on worker nodes
+func (aa *AppArmor) Doctor() (types.NodeList, types.Findings, error) {
+	nodes, err := aa.getNodes()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	findings := types.Findings{}
+
+	for _, node := range nodes {
+		findings = append(findings, aa.doctor(node)...)
+	}
+
+	return nodes, findings, nil
+}
+
+func (aa *AppArmor) doctor(node *types.Node) types.Findings {
+	if node.IsMaster() || node.Skipped() {
+		return nil
+	}
+
+	conn, ok := aa.connect(node)
+
+	if !ok {
+		return nil
+	}
+
+	defer conn.Close()
+
+	findings := types.Findings{}
+
+	for _, c := range checks {
+		stdout, stderr, err := conn.ExecuteOne(c.command, c.sudo)
+
+		var finding types.Finding
+
+		if err != nil {
+			finding = failed(fmt.Sprintf("failed to run the check: %v", err), "")
+		} else {
+			finding = c.evaluate(strings.TrimSpace(stdout), strings.TrimSpace(stderr))
+		}
+
+		finding.Node = node.NodeName
+		finding.Check = c.name
+
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+func passed(message string) types.Finding {
+	return types.Finding{Status: types.FindingOK, Message: message}
+}
+
+func warning(message, fix string) types.Finding {
+	return types.Finding{Status: types.FindingWarning, Message: message, Fix: fix}
+}
+
+func failed(message, fix string) types.Finding {
+	return types.Finding{Status: types.FindingError, Message: message, Fix: fix}
+}
+
+func parseKeyValues(s string) map[string]string {
+	values := map[string]string{}
+
+	for _, line := range strings.Split(s, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+
+	return values
+}
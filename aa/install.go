@@ -0,0 +1,329 @@
+package aa
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
+	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// InstallOptions controls how worker nodes are restarted once AppArmor is installed
+type InstallOptions struct {
+	// Drain cordons and drains Kubernetes nodes before restarting them
+	Drain bool
+	// MaxUnavailable is the number of nodes restarted at the same time
+	MaxUnavailable int
+	// DrainTimeout is how long to wait for the pods to be evicted, e.g. when blocked by pod disruption budgets
+	DrainTimeout time.Duration
+	// RebootTimeout is how long to wait for a node to be Ready with AppArmor enabled after the restart
+	RebootTimeout time.Duration
+}
+
+// DefaultInstallOptions restart one node at a time after draining it
+var DefaultInstallOptions = InstallOptions{
+	Drain:          true,
+	MaxUnavailable: 1,
+	DrainTimeout:   5 * time.Minute,
+	RebootTimeout:  10 * time.Minute,
+}
+
+const restartPollInterval = 10 * time.Second
+
+// InstallAppArmor installs AppArmor service on worker nodes, at most MaxUnavailable nodes are restarted at the same time
+// and the installation stops at the first node failing to come back
+func (aa *AppArmor) InstallAppArmor(opts InstallOptions) (types.NodeList, error) {
+	if opts.MaxUnavailable < 1 {
+		return nil, fmt.Errorf("max unavailable must be at least 1")
+	}
+
+	nodes, err := aa.getNodes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	targets := types.NodeList{}
+
+	for _, node := range nodes {
+		if !node.IsMaster() && !node.Skipped() {
+			targets = append(targets, node)
+		}
+	}
+
+	for i := 0; i < len(targets); i += opts.MaxUnavailable {
+		end := i + opts.MaxUnavailable
+		if end > len(targets) {
+			end = len(targets)
+		}
+
+		batch := targets[i:end]
+		errs := make([]error, len(batch))
+
+		var wg sync.WaitGroup
+
+		for j, node := range batch {
+			wg.Add(1)
+			go func(j int, node *types.Node) {
+				defer wg.Done()
+				errs[j] = aa.install(node, opts)
+			}(j, node)
+		}
+
+		wg.Wait()
+
+		for j, err := range errs {
+			if err != nil {
+				return nodes, fmt.Errorf("node %s: %v", batch[j].NodeName, err)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+func (aa *AppArmor) install(node *types.Node, opts InstallOptions) error {
+	conn, ok := aa.connect(node)
+
+	if !ok {
+		return nil
+	}
+
+	defer conn.Close()
+
+	if aa.enabledInConnection(conn, node) {
+		klog.Infof("AppArmor was enabled on node: %s (%s)", node.NodeName, aa.address(node))
+		return nil
+	}
+
+	cmds, err := aa.installCommands(conn)
+
+	if err != nil {
+		if unsupported, ok := err.(*commands.UnsupportedError); ok {
+			klog.Warningf("AppArmor can't be installed on node: %s, %s", node.NodeName, unsupported.Reason)
+			node.Skip(fmt.Sprintf("%s: %s", types.SkipUnsupported, unsupported.Reason))
+			return nil
+		}
+
+		return err
+	}
+
+	err = conn.ExecuteBatch(cmds, true)
+
+	if err != nil {
+		return err
+	}
+
+	return aa.restart(node, conn, opts)
+}
+
+// restart cordons and drains a Kubernetes node, restarts it, waits for it to come back with AppArmor enabled and uncordons it.
+// A node failing to come back is left cordoned.
+func (aa *AppArmor) restart(node *types.Node, conn *client.SSHClient, opts InstallOptions) error {
+	var k8s *client.K8sClient
+	wasCordoned := false
+
+	if opts.Drain && node.IsKubernetes() {
+		var err error
+
+		k8s, err = aa.kube()
+
+		if err != nil {
+			return err
+		}
+
+		klog.Infof("Cordoning and draining node: %s", node.NodeName)
+
+		wasCordoned, err = k8s.SetUnschedulable(node.NodeName, true)
+
+		if err != nil {
+			return fmt.Errorf("failed to cordon: %v", err)
+		}
+
+		err = k8s.Drain(node.NodeName, opts.DrainTimeout)
+
+		if err != nil {
+			if _, uncordonErr := k8s.SetUnschedulable(node.NodeName, wasCordoned); uncordonErr != nil {
+				klog.Warningf("Failed to uncordon node: %s: %v", node.NodeName, uncordonErr)
+			}
+
+			return fmt.Errorf("failed to drain, AppArmor is installed but the node was not restarted: %v", err)
+		}
+	}
+
+	klog.Infof("Restarting node: %s", node.NodeName)
+
+	err := conn.ExecuteBatch([]string{commands.Reboot}, true)
+
+	if err != nil {
+		return err
+	}
+
+	conn.Close()
+
+	err = aa.waitForRestart(node, opts.RebootTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Node: %s restarted with AppArmor enabled", node.NodeName)
+
+	if k8s != nil && !wasCordoned {
+		_, err = k8s.SetUnschedulable(node.NodeName, false)
+
+		if err != nil {
+			return fmt.Errorf("failed to uncordon: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// waitForRestart waits until a restarted node is Ready (for Kubernetes nodes) and reports AppArmor as enabled
+func (aa *AppArmor) waitForRestart(node *types.Node, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	if node.IsKubernetes() {
+		k8s, err := aa.kube()
+
+		if err != nil {
+			return err
+		}
+
+		err = k8s.WaitForNodeReboot(node.NodeName, node.BootID, timeout)
+
+		if err != nil {
+			return fmt.Errorf("node did not become Ready after the restart: %v", err)
+		}
+	}
+
+	err := wait.Poll(restartPollInterval, time.Until(deadline), func() (bool, error) {
+		conn, err := aa.dial(node)
+
+		if err != nil {
+			return false, nil
+		}
+
+		defer conn.Close()
+
+		return aa.enabledInConnection(conn, node), nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("AppArmor is not enabled after the restart: %v", err)
+	}
+
+	return nil
+}
+
+// installCommands detects the distribution and the bootloader of a node and returns the commands to install AppArmor
+func (aa *AppArmor) installCommands(conn *client.SSHClient) ([]string, error) {
+	stdout, stderr, err := conn.ExecuteOne(commands.DetectOSRelease, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stderr) > 0 {
+		return nil, &commands.UnsupportedError{Reason: fmt.Sprintf("failed to detect the operating system: %s", stderr)}
+	}
+
+	osr := types.ParseOSRelease(stdout)
+
+	installer, err := commands.NewInstaller(osr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Detected %s (%s family)", osr, installer.Family)
+
+	if installer.Available != "" {
+		stdout, _, err := conn.ExecuteOne(installer.Available, true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(stdout) == "" {
+			return nil, &commands.UnsupportedError{Reason: fmt.Sprintf("AppArmor packages are not available for %s", osr)}
+		}
+	}
+
+	bootCmds, err := aa.kernelCmdlineCommands(conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := []string{}
+	cmds = append(cmds, installer.Commands...)
+	cmds = append(cmds, bootCmds...)
+
+	return cmds, nil
+}
+
+// kernelCmdlineCommands returns the commands enabling AppArmor on the kernel command line of the detected bootloader,
+// no command is returned if it is already enabled
+func (aa *AppArmor) kernelCmdlineCommands(conn *client.SSHClient) ([]string, error) {
+	bootloader, _, err := conn.ExecuteOne(commands.DetectBootloader, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bootloader = strings.TrimSpace(bootloader)
+
+	if bootloader == commands.BootloaderImmutable {
+		return nil, &commands.UnsupportedError{Reason: "the boot configuration of immutable images can't be changed"}
+	}
+
+	kernelCmdline := commands.KernelCmdlineFor(bootloader)
+
+	if kernelCmdline == nil {
+		klog.Warningf("No supported bootloader found, the kernel command line is left unchanged")
+		return nil, nil
+	}
+
+	content, stderr, err := conn.ExecuteOne(kernelCmdline.Read, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stderr) > 0 {
+		return nil, fmt.Errorf("failed to read %s: %s", kernelCmdline.File, stderr)
+	}
+
+	kernelRelease, _, err := conn.ExecuteOne(commands.KernelRelease, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	activeLSMs, _, err := conn.ExecuteOne(commands.ActiveLSMs, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, changed, err := kernelCmdline.Edit(content, kernelRelease, activeLSMs)
+
+	if err != nil {
+		return nil, &commands.UnsupportedError{Reason: err.Error()}
+	}
+
+	if !changed {
+		klog.Infof("AppArmor is already enabled in %s", kernelCmdline.File)
+		return nil, nil
+	}
+
+	return kernelCmdline.ConfigureCommands(content), nil
+}
@@ -0,0 +1,17 @@
+package aa
+
+import (
+	"github.com/sysdiglabs/kube-apparmor-manager/lint"
+)
+
+// Lint checks the rules of the AppArmorProfile and AppArmorProfileFragment objects against security best practices,
+// from the local files if set, from the cluster otherwise
+func (aa *AppArmor) Lint() (lint.Findings, error) {
+	objects, err := aa.getProfileObjects()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lint.Lint(objects), nil
+}
@@ -0,0 +1,137 @@
+package aa
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// apparmorParser compiles the profiles for the local check
+const apparmorParser = "apparmor_parser"
+
+// RenderedProfile is a profile file as it is deployed on worker nodes
+type RenderedProfile struct {
+	Name string
+	Mode v1beta1.ProfileMode
+	Text string
+	// CheckErr is the error of the local apparmor_parser check, nil if it passed or didn't run
+	CheckErr error
+}
+
+// RenderOptions controls what is rendered
+type RenderOptions struct {
+	// Names are the profiles to render, all of them if empty
+	Names []string
+	// Check compiles the profiles with the local apparmor_parser, without loading them, if it is installed
+	Check bool
+}
+
+// Render returns the profile files deployed on worker nodes by sync, from the local files if set, from the cluster otherwise
+func (aa *AppArmor) Render(opts RenderOptions) ([]RenderedProfile, error) {
+	profiles, err := aa.getProfiles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fragments, err := aa.getFragments()
+
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]types.AppArmorProfile{}
+
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+
+	selected := profiles
+
+	if len(opts.Names) > 0 {
+		selected = []types.AppArmorProfile{}
+
+		for _, name := range opts.Names {
+			profile, ok := byName[name]
+
+			if !ok {
+				return nil, fmt.Errorf("profile %s not found", name)
+			}
+
+			selected = append(selected, profile)
+		}
+	}
+
+	check := opts.Check
+
+	if _, err := exec.LookPath(apparmorParser); check && err != nil {
+		klog.Warningf("%s is not installed, the profiles are not checked", apparmorParser)
+		check = false
+	}
+
+	rendered := []RenderedProfile{}
+
+	for _, profile := range selected {
+		r := RenderedProfile{Name: profile.Name, Mode: profile.Mode, Text: commands.ProfileFile(profile)}
+
+		if check {
+			r.CheckErr = checkProfile(profile, fragments)
+		}
+
+		rendered = append(rendered, r)
+	}
+
+	return rendered, nil
+}
+
+// checkProfile compiles a profile with the local apparmor_parser without loading it, the fragments it includes are
+// written to a temporary directory added to the include search path
+func checkProfile(profile types.AppArmorProfile, fragments []types.AppArmorProfileFragment) error {
+	dir, err := ioutil.TempDir("", "kube-apparmor-manager")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	err = os.MkdirAll(filepath.Join(dir, types.FragmentIncludeDir), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	for _, fragment := range fragments {
+		err := ioutil.WriteFile(filepath.Join(dir, types.FragmentInclude(fragment.Name)), []byte(commands.FragmentFile(fragment)), 0644)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	file := filepath.Join(dir, profile.Name)
+
+	err = ioutil.WriteFile(file, []byte(commands.ProfileFile(profile)), 0644)
+
+	if err != nil {
+		return err
+	}
+
+	// -Q skips loading the profile in the kernel, -K skips the cache
+	out, err := exec.Command(apparmorParser, "-Q", "-K", "-I", dir, file).CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("%s failed: %s", apparmorParser, strings.TrimSpace(strings.Replace(string(out), dir+"/", "", -1)))
+	}
+
+	return nil
+}
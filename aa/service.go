@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"k8s.io/klog"
 
 	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
 	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/crd"
+	"github.com/sysdiglabs/kube-apparmor-manager/inventory"
 	"github.com/sysdiglabs/kube-apparmor-manager/types"
 	"github.com/sysdiglabs/kube-apparmor-manager/utils"
 )
@@ -23,18 +26,22 @@ const (
 )
 
 type AppArmor struct {
-	k8sClient *client.K8sClient
-	sshClient *client.SSHClient
-	useInternalIP bool
+	k8sClient         *client.K8sClient
+	k8sErr            error
+	sshClient         *client.SSHClient
+	sshErr            error
+	addressPreference []string
+	nodeFilter        types.NodeFilter
+	inventory         *inventory.Inventory
+	inventoryMode     string
+	profileFiles      []string
 }
 
-// NewAppArmor returns a new AppArmor object
+// NewAppArmor returns a new AppArmor object, a missing Kubernetes or SSH configuration is only reported
+// when the cluster or the nodes are used, so that inventory hosts and local profile files work without
+// a cluster and the webhook works without SSH credentials
 func NewAppArmor() (*AppArmor, error) {
-	k8s, err := client.NewK8sClient()
-
-	if err != nil {
-		return nil, err
-	}
+	k8s, k8sErr := client.NewK8sClient()
 
 	username := os.Getenv(envSSHUsername)
 
@@ -50,157 +57,249 @@ func NewAppArmor() (*AppArmor, error) {
 
 	sshPassPhrase := os.Getenv(envSSHPassPhrase)
 
-	ssh, err := client.NewSSHClientConfig(username, sshPermFile, sshPassPhrase)
+	ssh, sshErr := client.NewSSHClientConfig(username, sshPermFile, sshPassPhrase)
 
-	if err != nil {
-		return nil, fmt.Errorf("error configuring SSH client, make sure you setup the credentials correctly")
+	if sshErr != nil {
+		sshErr = fmt.Errorf("error configuring SSH client, make sure you setup the credentials correctly: %v", sshErr)
 	}
+
 	return &AppArmor{
-		k8sClient: k8s,
-		sshClient: ssh,
-		useInternalIP: false,
+		k8sClient:         k8s,
+		k8sErr:            k8sErr,
+		sshClient:         ssh,
+		sshErr:            sshErr,
+		addressPreference: types.DefaultAddressPreference,
 	}, nil
 }
 
-func (aa *AppArmor) UseInternalIP(useInternalIP bool) {
-	aa.useInternalIP = useInternalIP
+// SetAddressPreference sets the order of node address types used to connect to nodes
+func (aa *AppArmor) SetAddressPreference(preference []string) {
+	aa.addressPreference = preference
 }
 
-// InstallCRD installs CRD in Kubernetes
-func (aa *AppArmor) InstallCRD() error {
-	return aa.k8sClient.InstallCRD()
+// SetNodeFilter sets the filter used to select the nodes to operate on
+func (aa *AppArmor) SetNodeFilter(filter types.NodeFilter) {
+	aa.nodeFilter = filter
 }
 
-// InstallAppArmor installs AppArmor service on worker nodes
-func (aa *AppArmor) InstallAppArmor() error {
-	nodes, err := aa.k8sClient.GetNodes()
+// SetInventory loads the inventory file which replaces or augments the Kubernetes nodes depending on the mode
+func (aa *AppArmor) SetInventory(path, mode string) error {
+	if mode != inventory.ModeReplace && mode != inventory.ModeAugment {
+		return fmt.Errorf("unknown inventory mode: %s", mode)
+	}
+
+	inv, err := inventory.Load(path)
 
 	if err != nil {
 		return err
 	}
 
-	for _, node := range nodes {
-		err = aa.install(node)
+	aa.inventory = inv
+	aa.inventoryMode = mode
+
+	return nil
+}
+
+// SetProfileFiles sets the local files or directories the profiles are read from instead of the cluster
+func (aa *AppArmor) SetProfileFiles(paths []string) {
+	aa.profileFiles = paths
+}
+
+// kube returns the Kubernetes client or the error which occurred creating it
+func (aa *AppArmor) kube() (*client.K8sClient, error) {
+	if aa.k8sErr != nil {
+		return nil, fmt.Errorf("Kubernetes cluster is not available: %v", aa.k8sErr)
+	}
+
+	return aa.k8sClient, nil
+}
+
+// KubeClient returns the Kubernetes client, e.g. for the webhook to read the cluster
+func (aa *AppArmor) KubeClient() (*client.K8sClient, error) {
+	return aa.kube()
+}
+
+// useCluster checks whether the nodes come from the Kubernetes cluster
+func (aa *AppArmor) useCluster() bool {
+	return aa.inventory == nil || aa.inventoryMode == inventory.ModeAugment
+}
+
+// getNodes returns the nodes selected by the node filter, nodes which can't be operated on are marked as skipped
+func (aa *AppArmor) getNodes() (types.NodeList, error) {
+	nodes := types.NodeList{}
+
+	if aa.useCluster() {
+		k8s, err := aa.kube()
 
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		nodes, err = k8s.GetNodes(aa.nodeFilter)
+
+		if err != nil {
+			return nil, err
 		}
 	}
-	return nil
-}
 
-func (aa *AppArmor) install(node *types.Node) error {
-	if node.IsMaster() {
-		return nil
+	if aa.inventory != nil {
+		hosts, err := aa.inventory.GetNodes(aa.nodeFilter)
+
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, hosts...)
 	}
 
-	var err error
-	if aa.useInternalIP {
-		err = aa.sshClient.Connect(node.InternalIP, SSH_PORT)
-	} else {
-		err = aa.sshClient.Connect(node.ExternalIP, SSH_PORT)
+	for _, node := range nodes {
+		if !node.Skipped() && !node.IsMaster() && aa.address(node) == "" {
+			node.Skip(types.SkipNoAddress)
+		}
 	}
 
+	return nodes, nil
+}
+
+func (aa *AppArmor) address(node *types.Node) string {
+	return node.Address(aa.addressPreference)
+}
+
+// getProfiles returns the AppArmor profiles from the local files if set, from the cluster otherwise
+func (aa *AppArmor) getProfiles() ([]types.AppArmorProfile, error) {
+	if len(aa.profileFiles) > 0 {
+		return client.LoadAppArmorProfiles(aa.profileFiles)
+	}
+
+	k8s, err := aa.kube()
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer aa.sshClient.Close()
+	return k8s.GetAppArmorProfiles()
+}
 
-	if aa.enabledInConnection(node) {
-		if aa.useInternalIP {
-			klog.Infof("AppArmor was enabled on node: %s (internal IP: %s)", node.NodeName, node.InternalIP)
-		} else {
-			klog.Infof("AppArmor was enabled on node: %s (external IP: %s)", node.NodeName, node.ExternalIP)
-		}
-		return nil
+// getFragments returns the profile fragments from the local files if set, from the cluster otherwise
+func (aa *AppArmor) getFragments() ([]types.AppArmorProfileFragment, error) {
+	if len(aa.profileFiles) > 0 {
+		return client.LoadAppArmorProfileFragments(aa.profileFiles)
 	}
 
-	err = aa.sshClient.ExecuteBatch(commands.InstallAppArmor, true)
+	k8s, err := aa.kube()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return k8s.GetAppArmorProfileFragments()
 }
 
-// Sync syncs AppArmor profiles from etcd to worker nodes
-func (aa *AppArmor) Sync() error {
-	nodes, err := aa.k8sClient.GetNodes()
+// getProfileObjects returns the AppArmorProfile and AppArmorProfileFragment objects as they are written, from the
+// local files if set, from the cluster otherwise
+func (aa *AppArmor) getProfileObjects() (*client.ProfileObjects, error) {
+	if len(aa.profileFiles) > 0 {
+		return client.LoadProfileObjects(aa.profileFiles)
+	}
+
+	k8s, err := aa.kube()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	profiles, err := aa.k8sClient.GetAppArmorProfiles()
+	return k8s.GetProfileObjects()
+}
 
-	if err != nil {
-		return err
+// dial returns a new SSH connection to a node
+func (aa *AppArmor) dial(node *types.Node) (*client.SSHClient, error) {
+	if aa.sshErr != nil {
+		return nil, aa.sshErr
 	}
 
-	for _, node := range nodes {
-		for _, profile := range profiles {
-			err := aa.syncProfile(node, profile)
-			if err != nil {
-				return err
-			}
-		}
+	port := SSH_PORT
+
+	if node.SSH != nil && node.SSH.Port != 0 {
+		port = strconv.Itoa(node.SSH.Port)
 	}
 
-	return nil
-}
+	conn, err := aa.sshClient.WithConfig(node.SSH)
 
-func (aa *AppArmor) syncProfile(node *types.Node, profile types.AppArmorProfile) error {
-	if node.IsMaster() {
-		return nil
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
-	if aa.useInternalIP {
-		err = aa.sshClient.Connect(node.InternalIP, SSH_PORT)
-	} else {
-		err = aa.sshClient.Connect(node.ExternalIP, SSH_PORT)
+	err = conn.Connect(aa.address(node), port)
+
+	if err != nil {
+		return nil, err
 	}
 
+	return conn, nil
+}
+
+// connect returns a new SSH connection to a node, the node is marked as unreachable if the connection fails
+func (aa *AppArmor) connect(node *types.Node) (*client.SSHClient, bool) {
+	conn, err := aa.dial(node)
+
 	if err != nil {
-		return err
+		klog.Warningf("Skipping node: %s (%s), failed to connect: %v", node.NodeName, aa.address(node), err)
+		node.Skip(fmt.Sprintf("%s: %v", types.SkipUnreachable, err))
+		return nil, false
 	}
 
-	defer aa.sshClient.Close()
+	return conn, true
+}
 
-	if !aa.enabledInConnection(node) {
-		if aa.useInternalIP {
-			klog.Infof("AppArmor was not enabled on node: %s (internal IP: %s), no sync happen.", node.NodeName, node.InternalIP)
-		} else {
-			klog.Infof("AppArmor was not enabled on node: %s (external IP: %s), no sync happen.", node.NodeName, node.ExternalIP)
+// InstallCRD installs CRD in Kubernetes and registers the validating webhook, nothing is done if only inventory
+// hosts are used and there is no cluster. Without the webhook only v1alpha1 is served.
+func (aa *AppArmor) InstallCRD(webhook *crd.WebhookConfig) error {
+	k8s, err := aa.kube()
+
+	if err != nil {
+		if !aa.useCluster() {
+			klog.Infof("Skipping CRD installation: %v", err)
+			return nil
 		}
-		return nil
+
+		return err
 	}
 
-	err = aa.sshClient.ExecuteBatch(commands.CreateProfileCommands(profile), true)
+	err = k8s.InstallCRD(webhook)
 
 	if err != nil {
 		return err
 	}
 
-	if profile.Enforced {
-		err = aa.sshClient.ExecuteBatch(commands.EnforceProfileCommands(profile), true)
-	} else {
-		// turn it into complain mode
-		err = aa.sshClient.ExecuteBatch(commands.ComplainProfileCommands(profile), true)
+	// without the webhook a stale registration would refuse all the objects
+	if webhook == nil {
+		klog.Warningln("No webhook configured, only v1alpha1 AppArmorProfile is served and objects are validated at sync")
+
+		err = k8s.RemoveMutatingWebhook()
+
+		if err != nil {
+			return err
+		}
+
+		return k8s.RemoveValidatingWebhook()
 	}
 
+	err = k8s.InstallValidatingWebhook(webhook)
+
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if !webhook.MutatePods {
+		return k8s.RemoveMutatingWebhook()
+	}
+
+	return k8s.InstallMutatingWebhook(webhook)
 }
 
 // AppArmorEnabled get AppArmor enabled status on worker nodes
 func (aa *AppArmor) AppArmorEnabled() (types.NodeList, error) {
-	nodes, err := aa.k8sClient.GetNodes()
+	nodes, err := aa.getNodes()
 
 	if err != nil {
 		return nil, err
@@ -217,27 +316,23 @@ func (aa *AppArmor) AppArmorEnabled() (types.NodeList, error) {
 }
 
 func (aa *AppArmor) enabled(node *types.Node) (bool, error) {
-	if node.IsMaster() {
+	if node.IsMaster() || node.Skipped() {
 		return false, nil
 	}
 
-	var err error
-	if aa.useInternalIP {
-		err = aa.sshClient.Connect(node.InternalIP, SSH_PORT)
-	} else {
-		err = aa.sshClient.Connect(node.ExternalIP, SSH_PORT)
-	}
-	if err != nil {
-		return false, err
+	conn, ok := aa.connect(node)
+
+	if !ok {
+		return false, nil
 	}
 
-	defer aa.sshClient.Close()
+	defer conn.Close()
 
-	return aa.enabledInConnection(node), nil
+	return aa.enabledInConnection(conn, node), nil
 }
 
-func (aa *AppArmor) enabledInConnection(node *types.Node) bool {
-	stdout, stderr, err := aa.sshClient.ExecuteOne(commands.AAEnable, true)
+func (aa *AppArmor) enabledInConnection(conn *client.SSHClient, node *types.Node) bool {
+	stdout, stderr, err := conn.ExecuteOne(commands.AAEnable, true)
 
 	if err != nil {
 		return false
@@ -257,7 +352,7 @@ func (aa *AppArmor) enabledInConnection(node *types.Node) bool {
 
 // AppArmorStatus gets AppArmor enforced profiles on worker nodes
 func (aa *AppArmor) AppArmorStatus() (types.NodeList, error) {
-	nodes, err := aa.k8sClient.GetNodes()
+	nodes, err := aa.getNodes()
 
 	if err != nil {
 		return nodes, err
@@ -275,34 +370,43 @@ func (aa *AppArmor) AppArmorStatus() (types.NodeList, error) {
 }
 
 func (aa *AppArmor) status(node *types.Node) error {
-	if node.IsMaster() {
+	if node.IsMaster() || node.Skipped() {
 		return nil
 	}
 
-	var err error
-	if aa.useInternalIP {
-		err = aa.sshClient.Connect(node.InternalIP, SSH_PORT)
-	} else {
-		err = aa.sshClient.Connect(node.ExternalIP, SSH_PORT)
-	}
-	if err != nil {
-		return err
+	conn, ok := aa.connect(node)
+
+	if !ok {
+		return nil
 	}
 
-	defer aa.sshClient.Close()
+	defer conn.Close()
 
-	if !aa.enabledInConnection(node) {
+	if !aa.enabledInConnection(conn, node) {
 		return nil
 	}
 
-	stdout, stderr, err := aa.sshClient.ExecuteOne(commands.AppArmorStatus, true)
+	status, err := readStatus(conn)
 
 	if err != nil {
 		return err
 	}
 
+	node.AppArmorStatus = status
+
+	return nil
+}
+
+// readStatus returns the profiles loaded on a node, as apparmor_status reports them
+func readStatus(conn *client.SSHClient) (*types.AppArmorProfileStatus, error) {
+	stdout, stderr, err := conn.ExecuteOne(commands.AppArmorStatus, true)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if len(stderr) > 0 {
-		return fmt.Errorf(stderr)
+		return nil, fmt.Errorf(stderr)
 	}
 
 	status := types.NewAppArmorStatus()
@@ -310,10 +414,8 @@ func (aa *AppArmor) status(node *types.Node) error {
 	err = json.Unmarshal([]byte(stdout), status)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	node.AppArmorStatus = status
-
-	return nil
+	return status, nil
 }
@@ -0,0 +1,521 @@
+package aa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+const (
+	profileStateKey  = "profile/"
+	fragmentStateKey = "fragment/"
+)
+
+// SyncOptions controls how profiles are synced to worker nodes
+type SyncOptions struct {
+	// Force redeploys every profile and fragment, even if the node recorded it as up to date
+	Force bool
+	// DeleteInUse removes the profiles of deleted objects even though running pods use them, they are kept
+	// loaded until the pods are gone otherwise
+	DeleteInUse bool
+}
+
+// Sync syncs AppArmor profiles and the fragments they include from etcd to worker nodes. Only the profiles
+// and fragments which changed since the last sync are deployed, the profiles including a changed fragment
+// are reloaded along with it. The profiles of the objects being deleted are removed from the nodes, then the
+// finalizer keeping the objects is released.
+func (aa *AppArmor) Sync(opts SyncOptions) (types.NodeList, error) {
+	nodes, err := aa.getNodes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := aa.getProfiles()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	profiles, deleted, err := aa.splitDeletedProfiles(all, opts)
+
+	if err != nil {
+		return nodes, err
+	}
+
+	fragments, err := aa.getFragments()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	fragmentHashes := map[string]string{}
+
+	for _, fragment := range fragments {
+		fragmentHashes[fragment.Name] = hash(fragment.String())
+	}
+
+	profiles = withFragments(profiles, fragmentHashes)
+
+	err = aa.addProfileFinalizers(profiles)
+
+	if err != nil {
+		return nodes, err
+	}
+
+	// loaded are the profiles apparmor_status reports on the synced nodes, nodes which couldn't be reached are
+	// left out, a node without AppArmor or whose status couldn't be read has no profiles confirmed
+	loaded := map[*types.Node]*types.AppArmorProfileStatus{}
+	// complete tells whether all the worker nodes were synced, the profiles of deleted objects may be left elsewhere,
+	// the nodes left out by the node filter are not
+	complete := !aa.nodeFilter.Restricts()
+	// failures are the profiles and fragments which failed to deploy or to be removed, by node
+	failures := []string{}
+
+	for _, node := range nodes {
+		if node.IsMaster() {
+			continue
+		}
+
+		if node.Skipped() {
+			complete = false
+			continue
+		}
+
+		conn, ok := aa.connect(node)
+
+		if !ok {
+			complete = false
+			continue
+		}
+
+		nodeFailures, err := aa.syncNode(conn, node, profiles, deleted, fragments, fragmentHashes, opts)
+
+		if err != nil {
+			conn.Close()
+			aa.updateProfileStatus(profiles, loaded)
+			return nodes, err
+		}
+
+		loaded[node] = aa.loadedProfiles(conn, node)
+
+		conn.Close()
+
+		for _, failure := range nodeFailures {
+			failures = append(failures, fmt.Sprintf("%s on node %s", failure, node.NodeName))
+		}
+
+		complete = complete && len(nodeFailures) == 0
+	}
+
+	aa.updateProfileStatus(profiles, loaded)
+	aa.releaseDeletedProfiles(deleted, complete)
+
+	if len(failures) > 0 {
+		return nodes, fmt.Errorf("failed to sync %s, they are retried at the next sync", strings.Join(failures, ", "))
+	}
+
+	return nodes, nil
+}
+
+// withFragments returns the profiles whose fragments all exist, the other ones are skipped
+func withFragments(profiles []types.AppArmorProfile, fragmentHashes map[string]string) []types.AppArmorProfile {
+	ret := []types.AppArmorProfile{}
+
+	for _, profile := range profiles {
+		missing := ""
+
+		for _, name := range profile.Fragments {
+			if _, ok := fragmentHashes[name]; !ok {
+				missing = name
+				break
+			}
+		}
+
+		if missing != "" {
+			klog.Warningf("Skipping profile %s which includes fragment %s, it doesn't exist or is invalid", profile.Name, missing)
+			continue
+		}
+
+		ret = append(ret, profile)
+	}
+
+	return ret
+}
+
+// splitDeletedProfiles returns the profiles to deploy and the profiles of deleted objects to remove from the nodes.
+// The profiles of deleted objects still used by running pods are kept deployed unless DeleteInUse is set.
+func (aa *AppArmor) splitDeletedProfiles(all []types.AppArmorProfile, opts SyncOptions) ([]types.AppArmorProfile, []types.AppArmorProfile, error) {
+	profiles := []types.AppArmorProfile{}
+	deleted := []types.AppArmorProfile{}
+
+	for _, profile := range all {
+		if profile.Deleted {
+			deleted = append(deleted, profile)
+		} else {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	if len(deleted) == 0 {
+		return profiles, deleted, nil
+	}
+
+	k8s, err := aa.kube()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods, err := k8s.ListPods()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the pods using deleted profiles: %v", err)
+	}
+
+	users := map[string][]string{}
+
+	for i := range pods {
+		pod := &pods[i]
+
+		if pod.Terminated() {
+			continue
+		}
+
+		for name := range pod.LocalhostProfiles() {
+			users[name] = append(users[name], pod.Metadata.Namespace+"/"+pod.Name())
+		}
+	}
+
+	removed := []types.AppArmorProfile{}
+
+	for _, profile := range deleted {
+		inUse := users[profile.Name]
+
+		if len(inUse) == 0 {
+			removed = append(removed, profile)
+			continue
+		}
+
+		sort.Strings(inUse)
+
+		if opts.DeleteInUse {
+			klog.Warningf("Removing profile %s of a deleted object, pods still use it: %s", profile.Name, strings.Join(inUse, ", "))
+			removed = append(removed, profile)
+			continue
+		}
+
+		klog.Warningf("Keeping profile %s of a deleted object until the pods using it are gone: %s", profile.Name, strings.Join(inUse, ", "))
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, removed, nil
+}
+
+// addProfileFinalizers sets the finalizer on the objects of the profiles about to be deployed, so that their profiles
+// are removed from the nodes when they are deleted
+func (aa *AppArmor) addProfileFinalizers(profiles []types.AppArmorProfile) error {
+	if len(aa.profileFiles) > 0 {
+		return nil
+	}
+
+	k8s, err := aa.kube()
+
+	if err != nil {
+		return err
+	}
+
+	for _, profile := range profiles {
+		if profile.Deleted {
+			continue
+		}
+
+		err := k8s.AddAppArmorProfileFinalizer(profile.Name)
+
+		if err != nil {
+			return fmt.Errorf("failed to add the finalizer of profile %s: %v", profile.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// releaseDeletedProfiles releases the finalizers of the deleted objects once their profiles are removed from all
+// the worker nodes, they are kept until the next sync otherwise
+func (aa *AppArmor) releaseDeletedProfiles(deleted []types.AppArmorProfile, complete bool) {
+	if len(deleted) == 0 {
+		return
+	}
+
+	if !complete {
+		klog.Warningf("Some worker nodes were not synced, the deleted objects are kept until their profiles are removed from all the nodes")
+		return
+	}
+
+	k8s, err := aa.kube()
+
+	if err != nil {
+		return
+	}
+
+	for _, profile := range deleted {
+		err := k8s.RemoveAppArmorProfileFinalizer(profile.Name)
+
+		if err != nil {
+			klog.Warningf("Failed to release the finalizer of profile %s: %v", profile.Name, err)
+			continue
+		}
+
+		klog.Infof("Profile %s removed from the worker nodes, its object is released", profile.Name)
+	}
+}
+
+// loadedProfiles confirms the profiles loaded on a node once it is synced, nil if AppArmor isn't enabled or the
+// status couldn't be read
+func (aa *AppArmor) loadedProfiles(conn *client.SSHClient, node *types.Node) *types.AppArmorProfileStatus {
+	if !node.AppArmorEnabled {
+		return nil
+	}
+
+	status, err := readStatus(conn)
+
+	if err != nil {
+		klog.Warningf("Failed to read the loaded profiles on node: %s, its profiles are not reported: %v", node.NodeName, err)
+		return nil
+	}
+
+	node.AppArmorStatus = status
+
+	return status
+}
+
+// updateProfileStatus records the Kubernetes nodes the profiles are confirmed loaded on in the status of the
+// objects, it is best effort since the profiles are already deployed
+func (aa *AppArmor) updateProfileStatus(profiles []types.AppArmorProfile, loaded map[*types.Node]*types.AppArmorProfileStatus) {
+	if len(aa.profileFiles) > 0 {
+		return
+	}
+
+	k8s, err := aa.kube()
+
+	if err != nil {
+		return
+	}
+
+	for _, profile := range profiles {
+		if profile.Deleted {
+			continue
+		}
+
+		err := k8s.UpdateAppArmorProfileNodes(profile.Name, func(current []string) []string {
+			set := map[string]bool{}
+
+			for _, name := range current {
+				set[name] = true
+			}
+
+			for node, status := range loaded {
+				if node.Source == types.SourceKubernetes {
+					set[node.NodeName] = status != nil && status.Profiles[profile.Name] != ""
+				}
+			}
+
+			nodes := []string{}
+
+			for name, ok := range set {
+				if ok {
+					nodes = append(nodes, name)
+				}
+			}
+
+			if len(nodes) == 0 {
+				return nil
+			}
+
+			sort.Strings(nodes)
+
+			return nodes
+		})
+
+		if err != nil {
+			klog.Warningf("Failed to update the status of profile %s: %v", profile.Name, err)
+		}
+	}
+}
+
+// syncNode deploys the profiles and fragments which changed on a node, it returns the ones which failed to deploy.
+// The hash of a profile or fragment is only recorded once it is deployed, the ones which failed are retried at the
+// next sync.
+func (aa *AppArmor) syncNode(conn *client.SSHClient, node *types.Node, profiles, deleted []types.AppArmorProfile, fragments []types.AppArmorProfileFragment, fragmentHashes map[string]string, opts SyncOptions) ([]string, error) {
+	if !aa.enabledInConnection(conn, node) {
+		klog.Infof("AppArmor was not enabled on node: %s (%s), no sync happen.", node.NodeName, aa.address(node))
+		return nil, nil
+	}
+
+	stdout, _, err := conn.ExecuteOne(commands.ReadSyncState, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := commands.ParseSyncState(stdout)
+	state := map[string]string{}
+	changed := map[string]bool{}
+	failures := []string{}
+
+	// keep records the hash deployed before, if any, of an entry which failed to deploy
+	keep := func(key string) {
+		if hash, ok := deployed[key]; ok {
+			state[key] = hash
+		}
+	}
+
+	// fragments are deployed first, the profiles including them are loaded afterwards
+	for _, fragment := range fragments {
+		key := fragmentStateKey + fragment.Name
+		hash := fragmentHashes[fragment.Name]
+
+		if !opts.Force && deployed[key] == hash {
+			state[key] = hash
+			continue
+		}
+
+		err := conn.ExecuteBatchChecked(commands.CreateFragmentCommands(fragment), true)
+
+		if err != nil {
+			klog.Warningf("Failed to deploy fragment %s on node: %s: %v", fragment.Name, node.NodeName, err)
+			failures = append(failures, "fragment "+fragment.Name)
+			keep(key)
+			continue
+		}
+
+		state[key] = hash
+		changed[fragment.Name] = true
+	}
+
+	for _, profile := range profiles {
+		key := profileStateKey + profile.Name
+		hash := profileHash(profile, fragmentHashes)
+
+		if !opts.Force && deployed[key] == hash {
+			state[key] = hash
+			continue
+		}
+
+		for _, name := range profile.Fragments {
+			if changed[name] && deployed[key] != "" {
+				klog.Infof("Reloading profile %s on node: %s, fragment %s changed", profile.Name, node.NodeName, name)
+				break
+			}
+		}
+
+		err := syncProfile(conn, profile)
+
+		if err != nil {
+			klog.Warningf("Failed to deploy profile %s on node: %s: %v", profile.Name, node.NodeName, err)
+			failures = append(failures, "profile "+profile.Name)
+			keep(key)
+			continue
+		}
+
+		state[key] = hash
+	}
+
+	// the profiles of deleted objects are removed whether or not the node recorded them, they may predate the state
+	for _, profile := range deleted {
+		err := conn.ExecuteBatchChecked(commands.RemoveProfileCommands(profile.Name), true)
+
+		if err != nil {
+			klog.Warningf("Failed to remove profile %s on node: %s: %v", profile.Name, node.NodeName, err)
+			failures = append(failures, "removal of profile "+profile.Name)
+		}
+	}
+
+	stdout, _, err = conn.ExecuteOne(commands.ListFragments, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fragmentNames := commands.ParseFragments(stdout)
+
+	stdout, _, err = conn.ExecuteOne(commands.ListFragmentIncludes, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a fragment which is gone is kept while profiles left deployed include it, e.g. the ones skipped as it's missing
+	included := commands.ParseFragmentIncludes(stdout)
+
+	for _, name := range fragmentNames {
+		if _, ok := fragmentHashes[name]; ok {
+			continue
+		}
+
+		if included[name] {
+			klog.Warningf("Keeping fragment %s on node: %s, deployed profiles still include it", name, node.NodeName)
+			continue
+		}
+
+		err := conn.ExecuteBatch(commands.RemoveFragmentCommands(name), true)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return failures, conn.ExecuteBatch(commands.WriteSyncStateCommands(state), true)
+}
+
+// syncProfile deploys and loads a profile in its mode, it fails if a command fails
+func syncProfile(conn *client.SSHClient, profile types.AppArmorProfile) error {
+	// a disabled profile is loaded nowhere
+	if profile.Mode == v1beta1.ModeDisable {
+		return conn.ExecuteBatchChecked(commands.RemoveProfileCommands(profile.Name), true)
+	}
+
+	err := conn.ExecuteBatchChecked(commands.CreateProfileCommands(profile), true)
+
+	if err != nil {
+		return err
+	}
+
+	switch profile.Mode {
+	case v1beta1.ModeComplain:
+		err = conn.ExecuteBatchChecked(commands.ComplainProfileCommands(profile), true)
+	case v1beta1.ModeKill, v1beta1.ModeUnconfined:
+		// the mode is a flag of the profile
+		err = conn.ExecuteBatchChecked(commands.LoadProfileCommands(profile), true)
+	default:
+		err = conn.ExecuteBatchChecked(commands.EnforceProfileCommands(profile), true)
+	}
+
+	return err
+}
+
+// profileHash returns the hash of a profile as deployed, it changes along with the fragments the profile includes
+func profileHash(profile types.AppArmorProfile, fragmentHashes map[string]string) string {
+	content := profile.String() + "\n" + string(profile.Mode) + "\n"
+
+	for _, name := range profile.Fragments {
+		content += name + " " + fragmentHashes[name] + "\n"
+	}
+
+	return hash(content)
+}
+
+func hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+package aa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/aa/commands"
+	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// UninstallOptions controls what is removed besides the profiles
+type UninstallOptions struct {
+	// RevertKernelCmdline restores the boot configuration backed up by init, it takes effect at the next restart
+	RevertKernelCmdline bool
+	// KeepCRD keeps the CRD and the AppArmorProfile objects in the cluster, it is required with a node filter
+	KeepCRD bool
+	// RemoveUnmanaged also removes the profiles named after an AppArmorProfile object which don't have the managed
+	// header, they are deployed by versions before the header was introduced or written by hand
+	RemoveUnmanaged bool
+}
+
+// Uninstall unloads and deletes the profiles and fragments created by the manager from worker nodes and deletes the CRDs
+func (aa *AppArmor) Uninstall(opts UninstallOptions) (types.NodeList, error) {
+	// the CRD, the objects and the webhooks serve the nodes left out by the filter as well
+	if aa.nodeFilter.Restricts() && !opts.KeepCRD {
+		return nil, fmt.Errorf("--keep-crd is required along with a node filter, the CRD is used by the other nodes")
+	}
+
+	nodes, err := aa.getNodes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// profiles created before the managed header was introduced are found through their objects
+	names := []string{}
+
+	if opts.RemoveUnmanaged {
+		profiles, err := aa.getProfiles()
+
+		if err != nil {
+			klog.Warningf("Failed to get the AppArmorProfile objects, only the profiles with the managed header are removed: %v", err)
+		}
+
+		for _, profile := range profiles {
+			names = append(names, profile.Name)
+		}
+	}
+
+	// complete tells whether all the worker nodes were cleaned, as for sync the finalizers are only released then,
+	// the nodes left out by the node filter are not
+	complete := !aa.nodeFilter.Restricts()
+
+	for _, node := range nodes {
+		cleaned, err := aa.uninstall(node, names, opts)
+
+		if err != nil {
+			return nodes, err
+		}
+
+		complete = complete && cleaned
+	}
+
+	// the inventory hosts are not part of a cluster in replace mode
+	if !aa.useCluster() {
+		return nodes, nil
+	}
+
+	k8s, err := aa.kube()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	if !complete {
+		klog.Warningf("Some worker nodes were filtered out, skipped or couldn't be reached, the finalizers of the AppArmorProfile objects are kept")
+
+		// a CRD whose objects hold finalizers is stuck terminating
+		if !opts.KeepCRD {
+			return nodes, fmt.Errorf("the CRD and the webhooks are kept until the profiles are removed from all the worker nodes, run uninstall again once they are reachable")
+		}
+
+		return nodes, nil
+	}
+
+	// the profiles are gone from the nodes, the objects must not wait for a sync to be deleted
+	err = k8s.RemoveAppArmorProfileFinalizers()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	if opts.KeepCRD {
+		return nodes, nil
+	}
+
+	err = k8s.RemoveMutatingWebhook()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	err = k8s.RemoveValidatingWebhook()
+
+	if err != nil {
+		return nodes, err
+	}
+
+	return nodes, k8s.RemoveCRD()
+}
+
+// uninstall removes the profiles from a node, it tells whether the node is cleaned, a skipped or unreachable
+// worker node is not
+func (aa *AppArmor) uninstall(node *types.Node, names []string, opts UninstallOptions) (bool, error) {
+	if node.IsMaster() {
+		return true, nil
+	}
+
+	if node.Skipped() {
+		return false, nil
+	}
+
+	conn, ok := aa.connect(node)
+
+	if !ok {
+		return false, nil
+	}
+
+	defer conn.Close()
+
+	stdout, _, err := conn.ExecuteOne(commands.ListManagedProfiles, true)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range mergeNames(names, commands.ParseManagedProfiles(stdout)) {
+		err := conn.ExecuteBatch(commands.RemoveProfileCommands(name), true)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = conn.ExecuteBatch(commands.RemoveManagedFiles, true)
+
+	if err != nil {
+		return false, err
+	}
+
+	if opts.RevertKernelCmdline {
+		return true, aa.revertKernelCmdline(conn, node)
+	}
+
+	return true, nil
+}
+
+// revertKernelCmdline restores the boot configuration from the backup made by init
+func (aa *AppArmor) revertKernelCmdline(conn *client.SSHClient, node *types.Node) error {
+	bootloader, _, err := conn.ExecuteOne(commands.DetectBootloader, true)
+
+	if err != nil {
+		return err
+	}
+
+	kernelCmdline := commands.KernelCmdlineFor(strings.TrimSpace(bootloader))
+
+	if kernelCmdline == nil {
+		klog.Infof("No supported bootloader found on node: %s, the kernel command line is left unchanged", node.NodeName)
+		return nil
+	}
+
+	stdout, _, err := conn.ExecuteOne(kernelCmdline.BackupExistsCommand(), true)
+
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(stdout) != "yes" {
+		klog.Infof("No backup of %s on node: %s, the kernel command line is left unchanged", kernelCmdline.File, node.NodeName)
+		return nil
+	}
+
+	err = conn.ExecuteBatch(kernelCmdline.RevertCommands(), true)
+
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Kernel command line reverted on node: %s, it takes effect at the next restart", node.NodeName)
+
+	return nil
+}
+
+// mergeNames returns the sorted union of the profile names
+func mergeNames(a, b []string) []string {
+	set := map[string]bool{}
+
+	for _, name := range append(append([]string{}, a...), b...) {
+		set[name] = true
+	}
+
+	names := []string{}
+
+	for name := range set {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -2,18 +2,34 @@ package v1alpha1
 
 import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+// AppArmorProfileSpec defines the desired state of AppArmorProfile
 type AppArmorProfileSpec struct {
-	Rules    string `json:"rules"`
-	Enforced bool   `json:"enforced"`
+	// AppArmor profile rules
+	Rules string `json:"rules"`
+	// Determine whether the AppArmor profile is enforced
+	Enforced bool `json:"enforced"`
 }
 
+// AppArmorProfileStatus defines the observed state of AppArmorProfile
+type AppArmorProfileStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=aap
+// +kubebuilder:subresource:status
+
+// AppArmorProfile is the Schema for the AppArmorprofiles API
 type AppArmorProfile struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec AppArmorProfileSpec `json:"spec"`
+	Spec   AppArmorProfileSpec   `json:"spec"`
+	Status AppArmorProfileStatus `json:"status,omitempty"`
 }
 
+// +kubebuilder:object:root=true
+
+// AppArmorProfileList contains a list of AppArmorProfile
 type AppArmorProfileList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
@@ -6,11 +6,12 @@ import "k8s.io/apimachinery/pkg/runtime"
 // same type that is provided as a pointer.
 func (in *AppArmorProfile) DeepCopyInto(out *AppArmorProfile) {
 	out.TypeMeta = in.TypeMeta
-	out.ObjectMeta = in.ObjectMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = AppArmorProfileSpec{
 		Rules:    in.Spec.Rules,
 		Enforced: in.Spec.Enforced,
 	}
+	out.Status = AppArmorProfileStatus{}
 }
 
 // DeepCopyObject returns a generically typed copy of an object
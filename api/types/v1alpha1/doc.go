@@ -0,0 +1,4 @@
+// Package v1alpha1 contains the v1alpha1 AppArmorProfile API
+// +kubebuilder:object:generate=false
+// +groupName=crd.security.sysdig.com
+package v1alpha1
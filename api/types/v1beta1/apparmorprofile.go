@@ -0,0 +1,124 @@
+package v1beta1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// ProfileMode is the mode an AppArmor profile is loaded in on worker nodes
+// +kubebuilder:validation:Enum=enforce;complain;disable;kill;unconfined
+type ProfileMode string
+
+const (
+	// ModeEnforce blocks and logs the violations
+	ModeEnforce ProfileMode = "enforce"
+	// ModeComplain only logs the violations
+	ModeComplain ProfileMode = "complain"
+	// ModeDisable unloads the profile from all the worker nodes, the object is kept in the cluster
+	ModeDisable ProfileMode = "disable"
+	// ModeKill kills the processes violating the profile, requires AppArmor 3.1 or newer on the nodes
+	ModeKill ProfileMode = "kill"
+	// ModeUnconfined attaches the profile without confining the processes, requires AppArmor 4 or newer on the nodes
+	ModeUnconfined ProfileMode = "unconfined"
+)
+
+// ProfileFinalizer is set on the AppArmorProfile objects deployed by sync, it is released once the profile of a
+// deleted object is removed from all the worker nodes
+const ProfileFinalizer = "crd.security.sysdig.com/node-cleanup"
+
+// ProfileFlag is an AppArmor profile flag, e.g. attach_disconnected or error=EPERM
+// +kubebuilder:validation:Pattern=`^[a-z_]+(=[A-Za-z0-9_]+)?$`
+type ProfileFlag string
+
+// PolicyPath is a path relative to the AppArmor policy directory, e.g. tunables/global
+// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.+-]+(/[A-Za-z0-9_.+-]+)*$`
+type PolicyPath string
+
+// FragmentName is the name of an AppArmorProfileFragment object
+// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+type FragmentName string
+
+// DefaultFlags are the flags of a profile which doesn't set any, they keep the containers working
+// when their files are deleted or reached from outside of their mount namespace
+var DefaultFlags = []ProfileFlag{"attach_disconnected", "mediate_deleted"}
+
+// AppArmorProfileSpec defines the desired state of AppArmorProfile
+type AppArmorProfileSpec struct {
+	// AppArmor profile rules, the profile declaration is generated around them
+	// +optional
+	Rules string `json:"rules,omitempty"`
+	// Profile is a complete profile text deployed verbatim instead of the rules, e.g. an upstream profile
+	// with hats, variables or includes. It must declare a profile named after the object.
+	// +optional
+	Profile string `json:"profile,omitempty"`
+	// Mode the AppArmor profile is loaded in on worker nodes
+	// +kubebuilder:default=enforce
+	// +optional
+	Mode ProfileMode `json:"mode,omitempty"`
+	// Profile flags, attach_disconnected and mediate_deleted if not set, an empty list sets no flags
+	// +nullable
+	// +optional
+	Flags []ProfileFlag `json:"flags"`
+	// Attachment is the executable path glob the profile is attached to, e.g. /usr/bin/**
+	// +kubebuilder:validation:Pattern=`^(/|@\{)\S*$`
+	// +optional
+	Attachment string `json:"attachment,omitempty"`
+	// ABI the profile is written for, e.g. abi/3.0
+	// +optional
+	ABI PolicyPath `json:"abi,omitempty"`
+	// Includes are included before the profile, e.g. tunables/global for the variables used by the rules
+	// +optional
+	Includes []PolicyPath `json:"includes,omitempty"`
+	// Fragments are the names of the AppArmorProfileFragment objects included in the profile
+	// +optional
+	Fragments []FragmentName `json:"fragments,omitempty"`
+	// Structured rules, rendered before the rules
+	RuleSet `json:",inline"`
+}
+
+// AppArmorProfileStatus defines the observed state of AppArmorProfile
+type AppArmorProfileStatus struct {
+	// Nodes are the Kubernetes nodes the profile was loaded on by the last sync
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=aap
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+
+// AppArmorProfile is the Schema for the AppArmorprofiles API
+type AppArmorProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AppArmorProfileSpec   `json:"spec"`
+	Status AppArmorProfileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppArmorProfileList contains a list of AppArmorProfile
+type AppArmorProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AppArmorProfile `json:"items"`
+}
+
+// GetMode returns the mode of the profile, enforce if it is not set
+func (s AppArmorProfileSpec) GetMode() ProfileMode {
+	if s.Mode == "" {
+		return ModeEnforce
+	}
+
+	return s.Mode
+}
+
+// GetFlags returns the flags of the profile, the default flags if they are not set
+func (s AppArmorProfileSpec) GetFlags() []ProfileFlag {
+	if s.Flags == nil {
+		return DefaultFlags
+	}
+
+	return s.Flags
+}
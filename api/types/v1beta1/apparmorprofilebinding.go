@@ -0,0 +1,58 @@
+package v1beta1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+const (
+	// BindingKind is the kind of AppArmorProfileBinding objects
+	BindingKind = "AppArmorProfileBinding"
+
+	// BindingCRDName is the name of the AppArmorProfileBinding CRD
+	BindingCRDName = "apparmorprofilebindings.crd.security.sysdig.com"
+
+	// BindingPlural is the resource of AppArmorProfileBinding objects
+	BindingPlural = "apparmorprofilebindings"
+)
+
+// ContainerBinding assigns a profile to the containers matching a name pattern
+type ContainerBinding struct {
+	// Name is a shell pattern matching the container names, e.g. nginx or sidecar-*, all containers if not set
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Profile is the name of the AppArmorProfile assigned to the containers
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Profile string `json:"profile"`
+}
+
+// AppArmorProfileBindingSpec selects pods and assigns profiles to their containers
+type AppArmorProfileBindingSpec struct {
+	// NamespaceSelector selects the namespaces of the pods, all namespaces if not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects the pods by label, all pods if not set
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// Containers assign profiles by container name, the first matching entry applies
+	// +kubebuilder:validation:MinItems=1
+	Containers []ContainerBinding `json:"containers"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=aapb
+
+// AppArmorProfileBinding assigns AppArmor profiles to the containers of the pods it selects when they are created
+type AppArmorProfileBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AppArmorProfileBindingSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppArmorProfileBindingList contains a list of AppArmorProfileBinding
+type AppArmorProfileBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AppArmorProfileBinding `json:"items"`
+}
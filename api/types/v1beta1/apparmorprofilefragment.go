@@ -0,0 +1,44 @@
+package v1beta1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+const (
+	// FragmentKind is the kind of AppArmorProfileFragment objects
+	FragmentKind = "AppArmorProfileFragment"
+
+	// FragmentCRDName is the name of the AppArmorProfileFragment CRD
+	FragmentCRDName = "apparmorprofilefragments.crd.security.sysdig.com"
+
+	// FragmentPlural is the resource of AppArmorProfileFragment objects
+	FragmentPlural = "apparmorprofilefragments"
+)
+
+// AppArmorProfileFragmentSpec defines the rules shared by the profiles including the fragment
+type AppArmorProfileFragmentSpec struct {
+	// AppArmor rules, as the rules of a profile
+	// +optional
+	Rules string `json:"rules,omitempty"`
+	// Structured rules, rendered before the rules
+	RuleSet `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=aapf
+
+// AppArmorProfileFragment is a set of rules deployed as an abstraction on worker nodes and included by profiles
+type AppArmorProfileFragment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AppArmorProfileFragmentSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppArmorProfileFragmentList contains a list of AppArmorProfileFragment
+type AppArmorProfileFragmentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AppArmorProfileFragment `json:"items"`
+}
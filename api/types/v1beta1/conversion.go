@@ -0,0 +1,95 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+)
+
+const (
+	// SpecAnnotation keeps the v1beta1 spec on v1alpha1 objects, so that what v1alpha1 can't express
+	// (e.g. the disable mode) survives a round trip through v1alpha1
+	SpecAnnotation = "crd.security.sysdig.com/v1beta1-spec"
+)
+
+// ConvertFromV1alpha1 converts a v1alpha1 profile into a v1beta1 profile, enforced maps to the enforce mode
+// and not enforced to the complain mode, unless the mode kept in the spec annotation agrees with enforced
+func ConvertFromV1alpha1(in *v1alpha1.AppArmorProfile, out *AppArmorProfile) error {
+	out.TypeMeta = metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: v1alpha1.Kind}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = AppArmorProfileSpec{}
+	out.Status = AppArmorProfileStatus{}
+
+	if spec, ok := out.Annotations[SpecAnnotation]; ok {
+		err := json.Unmarshal([]byte(spec), &out.Spec)
+
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation: %v", SpecAnnotation, err)
+		}
+
+		delete(out.Annotations, SpecAnnotation)
+
+		if len(out.Annotations) == 0 {
+			out.Annotations = nil
+		}
+	}
+
+	out.Spec.Rules = in.Spec.Rules
+
+	// enforced wins if it was changed through v1alpha1
+	if in.Spec.Enforced != (out.Spec.GetMode() == ModeEnforce) {
+		if in.Spec.Enforced {
+			out.Spec.Mode = ModeEnforce
+		} else {
+			out.Spec.Mode = ModeComplain
+		}
+	}
+
+	out.Spec.Mode = out.Spec.GetMode()
+
+	return nil
+}
+
+// ConvertToV1alpha1 converts a v1beta1 profile into a v1alpha1 profile, only the enforce mode maps to enforced.
+// The spec is kept in an annotation if v1alpha1 can't express it.
+func ConvertToV1alpha1(in *AppArmorProfile, out *v1alpha1.AppArmorProfile) error {
+	out.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: v1alpha1.Kind}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = v1alpha1.AppArmorProfileSpec{
+		Rules:    in.Spec.Rules,
+		Enforced: in.Spec.GetMode() == ModeEnforce,
+	}
+	out.Status = v1alpha1.AppArmorProfileStatus{}
+
+	spec := in.Spec
+	spec.Rules = ""
+	spec.Mode = spec.GetMode()
+
+	// what converting back without the annotation would give
+	lossy := AppArmorProfileSpec{Mode: ModeComplain}
+	if out.Spec.Enforced {
+		lossy.Mode = ModeEnforce
+	}
+
+	if reflect.DeepEqual(spec, lossy) {
+		return nil
+	}
+
+	data, err := json.Marshal(spec)
+
+	if err != nil {
+		return err
+	}
+
+	if out.Annotations == nil {
+		out.Annotations = map[string]string{}
+	}
+
+	out.Annotations[SpecAnnotation] = string(data)
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package v1beta1
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// DeepCopyInto copies all properties of this object into another object of the
+// same type that is provided as a pointer.
+func (in *AppArmorProfile) DeepCopyInto(out *AppArmorProfile) {
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = AppArmorProfileStatus{}
+
+	if in.Status.Nodes != nil {
+		out.Status.Nodes = make([]string, len(in.Status.Nodes))
+		copy(out.Status.Nodes, in.Status.Nodes)
+	}
+}
+
+// DeepCopyInto copies the spec into another spec
+func (in *AppArmorProfileSpec) DeepCopyInto(out *AppArmorProfileSpec) {
+	*out = *in
+
+	if in.Flags != nil {
+		out.Flags = make([]ProfileFlag, len(in.Flags))
+		copy(out.Flags, in.Flags)
+	}
+
+	if in.Includes != nil {
+		out.Includes = make([]PolicyPath, len(in.Includes))
+		copy(out.Includes, in.Includes)
+	}
+
+	in.RuleSet.DeepCopyInto(&out.RuleSet)
+
+	if in.Fragments != nil {
+		out.Fragments = make([]FragmentName, len(in.Fragments))
+		copy(out.Fragments, in.Fragments)
+	}
+}
+
+// DeepCopyInto copies the rule set into another rule set
+func (in *RuleSet) DeepCopyInto(out *RuleSet) {
+	*out = *in
+
+	if in.Files != nil {
+		out.Files = make([]FileRule, len(in.Files))
+		copy(out.Files, in.Files)
+	}
+
+	if in.Capabilities != nil {
+		out.Capabilities = make([]CapabilityRule, len(in.Capabilities))
+		for i := range in.Capabilities {
+			out.Capabilities[i] = in.Capabilities[i]
+			out.Capabilities[i].Names = append([]Capability(nil), in.Capabilities[i].Names...)
+		}
+	}
+
+	if in.Network != nil {
+		out.Network = make([]NetworkRule, len(in.Network))
+		copy(out.Network, in.Network)
+	}
+
+	if in.Signal != nil {
+		out.Signal = make([]SignalRule, len(in.Signal))
+		for i := range in.Signal {
+			out.Signal[i] = in.Signal[i]
+			out.Signal[i].Access = append([]SignalAccess(nil), in.Signal[i].Access...)
+			out.Signal[i].Signals = append([]Signal(nil), in.Signal[i].Signals...)
+		}
+	}
+
+	if in.Ptrace != nil {
+		out.Ptrace = make([]PtraceRule, len(in.Ptrace))
+		for i := range in.Ptrace {
+			out.Ptrace[i] = in.Ptrace[i]
+			out.Ptrace[i].Access = append([]PtraceAccess(nil), in.Ptrace[i].Access...)
+		}
+	}
+
+	if in.Mount != nil {
+		out.Mount = make([]MountRule, len(in.Mount))
+		for i := range in.Mount {
+			out.Mount[i] = in.Mount[i]
+			out.Mount[i].Options = append([]MountOption(nil), in.Mount[i].Options...)
+		}
+	}
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfile) DeepCopyObject() runtime.Object {
+	out := AppArmorProfile{}
+	in.DeepCopyInto(&out)
+
+	return &out
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfileList) DeepCopyObject() runtime.Object {
+	out := AppArmorProfileList{}
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+
+	if in.Items != nil {
+		out.Items = make([]AppArmorProfile, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+
+	return &out
+}
+
+// DeepCopyInto copies all properties of this object into another object of the
+// same type that is provided as a pointer.
+func (in *AppArmorProfileFragment) DeepCopyInto(out *AppArmorProfileFragment) {
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Rules = in.Spec.Rules
+	in.Spec.RuleSet.DeepCopyInto(&out.Spec.RuleSet)
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfileFragment) DeepCopyObject() runtime.Object {
+	out := AppArmorProfileFragment{}
+	in.DeepCopyInto(&out)
+
+	return &out
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfileFragmentList) DeepCopyObject() runtime.Object {
+	out := AppArmorProfileFragmentList{}
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+
+	if in.Items != nil {
+		out.Items = make([]AppArmorProfileFragment, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+
+	return &out
+}
+
+// DeepCopyInto copies all properties of this object into another object of the
+// same type that is provided as a pointer.
+func (in *AppArmorProfileBinding) DeepCopyInto(out *AppArmorProfileBinding) {
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = AppArmorProfileBindingSpec{
+		NamespaceSelector: in.Spec.NamespaceSelector.DeepCopy(),
+		PodSelector:       in.Spec.PodSelector.DeepCopy(),
+	}
+
+	if in.Spec.Containers != nil {
+		out.Spec.Containers = make([]ContainerBinding, len(in.Spec.Containers))
+		copy(out.Spec.Containers, in.Spec.Containers)
+	}
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfileBinding) DeepCopyObject() runtime.Object {
+	out := AppArmorProfileBinding{}
+	in.DeepCopyInto(&out)
+
+	return &out
+}
+
+// DeepCopyObject returns a generically typed copy of an object
+func (in *AppArmorProfileBindingList) DeepCopyObject() runtime.Object {
+	out := AppArmorProfileBindingList{}
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+
+	if in.Items != nil {
+		out.Items = make([]AppArmorProfileBinding, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+
+	return &out
+}
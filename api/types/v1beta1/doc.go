@@ -0,0 +1,4 @@
+// Package v1beta1 contains the v1beta1 AppArmorProfile API, it is the storage version and the hub of the conversions
+// +kubebuilder:object:generate=false
+// +groupName=crd.security.sysdig.com
+package v1beta1
@@ -0,0 +1,42 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+)
+
+const (
+	GroupVersion = "v1beta1"
+
+	// Plural is the resource of AppArmorProfile objects
+	Plural = "apparmorprofiles"
+)
+
+var SchemeGroupVersion = schema.GroupVersion{Group: v1alpha1.GroupName, Version: GroupVersion}
+
+// Resource takes an unqualified resource and returns a group qualified resource
+func Resource(resource string) schema.GroupResource {
+	return SchemeGroupVersion.WithResource(resource).GroupResource()
+}
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	AddToScheme   = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&AppArmorProfile{},
+		&AppArmorProfileList{},
+		&AppArmorProfileFragment{},
+		&AppArmorProfileFragmentList{},
+		&AppArmorProfileBinding{},
+		&AppArmorProfileBindingList{},
+	)
+
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
@@ -0,0 +1,267 @@
+package v1beta1
+
+import (
+	"strings"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
+)
+
+// RuleQualifiers change how a rule applies
+type RuleQualifiers struct {
+	// Deny denies the access instead of allowing it
+	// +optional
+	Deny bool `json:"deny,omitempty"`
+	// Audit logs the access
+	// +optional
+	Audit bool `json:"audit,omitempty"`
+}
+
+// FileRule allows or denies access to files, e.g. owner /tmp/** rw,
+type FileRule struct {
+	RuleQualifiers `json:",inline"`
+	// Path glob, e.g. /etc/** or @{HOME}/.cache/**
+	// +kubebuilder:validation:Pattern=`^(/|@\{)\S*$`
+	Path string `json:"path"`
+	// Permissions, e.g. rw or ix
+	// +kubebuilder:validation:Pattern=`^[rwaxlkmiuUpPcCD]+$`
+	Permissions string `json:"permissions"`
+	// Owner limits the rule to the files owned by the user of the process
+	// +optional
+	Owner bool `json:"owner,omitempty"`
+}
+
+// Capability is a Linux capability name without the CAP_ prefix
+// +kubebuilder:validation:Enum=audit_control;audit_read;audit_write;block_suspend;bpf;checkpoint_restore;chown;dac_override;dac_read_search;fowner;fsetid;ipc_lock;ipc_owner;kill;lease;linux_immutable;mac_admin;mac_override;mknod;net_admin;net_bind_service;net_broadcast;net_raw;perfmon;setfcap;setgid;setpcap;setuid;sys_admin;sys_boot;sys_chroot;sys_module;sys_nice;sys_pacct;sys_ptrace;sys_rawio;sys_resource;sys_time;sys_tty_config;syslog;wake_alarm
+type Capability string
+
+// CapabilityRule allows or denies capabilities, e.g. capability net_raw setuid,
+type CapabilityRule struct {
+	RuleQualifiers `json:",inline"`
+	// +kubebuilder:validation:MinItems=1
+	Names []Capability `json:"names"`
+}
+
+// NetworkRule allows or denies network access, a rule without family and type allows all network access
+type NetworkRule struct {
+	RuleQualifiers `json:",inline"`
+	// Family is the address family, e.g. inet, inet6 or unix
+	// +kubebuilder:validation:Pattern=`^[a-z0-9_]+$`
+	// +optional
+	Family string `json:"family,omitempty"`
+	// Type is the socket type, of all the families if family is not set, e.g. network raw,
+	// +kubebuilder:validation:Enum=stream;dgram;seqpacket;rdm;raw;packet
+	// +optional
+	Type string `json:"type,omitempty"`
+}
+
+// SignalAccess is a signal permission
+// +kubebuilder:validation:Enum=send;receive
+type SignalAccess string
+
+// SignalRule allows or denies sending and receiving signals, e.g. signal (send) set=(term) peer=foo,
+type SignalRule struct {
+	RuleQualifiers `json:",inline"`
+	// Access is send, receive or both if not set
+	// +optional
+	Access []SignalAccess `json:"access,omitempty"`
+	// Signals, e.g. term, kill or rtmin+1, all signals if not set
+	// +optional
+	Signals []Signal `json:"signals,omitempty"`
+	// Peer is the profile of the other process, any profile if not set
+	// +optional
+	Peer PeerLabel `json:"peer,omitempty"`
+}
+
+// Signal is a signal name without the SIG prefix
+// +kubebuilder:validation:Pattern=`^([a-z0-9]+|rtmin\+[0-9]+)$`
+type Signal string
+
+// PeerLabel is the profile name of a peer process
+// +kubebuilder:validation:Pattern=`^[^\s,()]+$`
+type PeerLabel string
+
+// PtraceAccess is a ptrace permission
+// +kubebuilder:validation:Enum=read;trace;readby;tracedby
+type PtraceAccess string
+
+// PtraceRule allows or denies tracing processes, e.g. ptrace (read) peer=foo,
+type PtraceRule struct {
+	RuleQualifiers `json:",inline"`
+	// Access, all permissions if not set
+	// +optional
+	Access []PtraceAccess `json:"access,omitempty"`
+	// Peer is the profile of the other process, any profile if not set
+	// +optional
+	Peer PeerLabel `json:"peer,omitempty"`
+}
+
+// MountOption is a mount option, e.g. ro or bind
+// +kubebuilder:validation:Pattern=`^[a-z0-9_=-]+$`
+type MountOption string
+
+// MountRule allows or denies mounting file systems, e.g. mount fstype=tmpfs options=(ro) none -> /mnt/,
+type MountRule struct {
+	RuleQualifiers `json:",inline"`
+	// FSType is the file system type, any type if not set
+	// +kubebuilder:validation:Pattern=`^[a-z0-9_.]+$`
+	// +optional
+	FSType string `json:"fstype,omitempty"`
+	// Options, any options if not set
+	// +optional
+	Options []MountOption `json:"options,omitempty"`
+	// Source is the mounted device or directory glob, any source if not set
+	// +kubebuilder:validation:Pattern=`^\S+$`
+	// +optional
+	Source string `json:"source,omitempty"`
+	// MountPoint glob, any mount point if not set
+	// +kubebuilder:validation:Pattern=`^(/|@\{)\S*$`
+	// +optional
+	MountPoint string `json:"mountPoint,omitempty"`
+}
+
+// RuleSet contains structured rules
+type RuleSet struct {
+	// Files are structured file rules
+	// +optional
+	Files []FileRule `json:"files,omitempty"`
+	// Capabilities are structured capability rules
+	// +optional
+	Capabilities []CapabilityRule `json:"capabilities,omitempty"`
+	// Network are structured network rules
+	// +optional
+	Network []NetworkRule `json:"network,omitempty"`
+	// Signal are structured signal rules
+	// +optional
+	Signal []SignalRule `json:"signal,omitempty"`
+	// Ptrace are structured ptrace rules
+	// +optional
+	Ptrace []PtraceRule `json:"ptrace,omitempty"`
+	// Mount are structured mount rules
+	// +optional
+	Mount []MountRule `json:"mount,omitempty"`
+}
+
+// IsEmpty checks whether no structured rule is set
+func (s RuleSet) IsEmpty() bool {
+	return !(len(s.Files) > 0 || len(s.Capabilities) > 0 || len(s.Network) > 0 || len(s.Signal) > 0 ||
+		len(s.Ptrace) > 0 || len(s.Mount) > 0)
+}
+
+// PolicyRules returns the structured rules as policy rules, in the order of the fields
+func (s RuleSet) PolicyRules() []policy.Rule {
+	rules := []policy.Rule{}
+
+	for _, r := range s.Files {
+		q := r.qualifiers()
+		q.Owner = r.Owner
+		rules = append(rules, &policy.FileRule{Qualifiers: q, Path: r.Path, Perms: r.Permissions})
+	}
+
+	for _, r := range s.Capabilities {
+		names := []string{}
+
+		for _, n := range r.Names {
+			names = append(names, string(n))
+		}
+
+		rules = append(rules, &policy.CapabilityRule{Qualifiers: r.qualifiers(), Capabilities: names})
+	}
+
+	for _, r := range s.Network {
+		args := []string{}
+
+		for _, a := range []string{r.Family, r.Type} {
+			if a != "" {
+				args = append(args, a)
+			}
+		}
+
+		rules = append(rules, &policy.NetworkRule{Qualifiers: r.qualifiers(), Args: args})
+	}
+
+	for _, r := range s.Signal {
+		rule := &policy.MediationRule{Qualifiers: r.qualifiers(), Keyword: "signal"}
+
+		for _, a := range r.Access {
+			rule.Access = append(rule.Access, string(a))
+		}
+
+		rule.AccessList = len(rule.Access) > 0
+
+		if len(r.Signals) > 0 {
+			c := policy.Conditional{Key: "set", List: true}
+
+			for _, sig := range r.Signals {
+				c.Values = append(c.Values, string(sig))
+			}
+
+			rule.Conds = append(rule.Conds, c)
+		}
+
+		if r.Peer != "" {
+			rule.Conds = append(rule.Conds, policy.Conditional{Key: "peer", Values: []string{string(r.Peer)}})
+		}
+
+		rules = append(rules, rule)
+	}
+
+	for _, r := range s.Ptrace {
+		rule := &policy.MediationRule{Qualifiers: r.qualifiers(), Keyword: "ptrace"}
+
+		for _, a := range r.Access {
+			rule.Access = append(rule.Access, string(a))
+		}
+
+		rule.AccessList = len(rule.Access) > 0
+
+		if r.Peer != "" {
+			rule.Conds = append(rule.Conds, policy.Conditional{Key: "peer", Values: []string{string(r.Peer)}})
+		}
+
+		rules = append(rules, rule)
+	}
+
+	for _, r := range s.Mount {
+		rule := &policy.MediationRule{Qualifiers: r.qualifiers(), Keyword: "mount", Target: r.MountPoint}
+
+		if r.FSType != "" {
+			rule.Conds = append(rule.Conds, policy.Conditional{Key: "fstype", Values: []string{r.FSType}})
+		}
+
+		if len(r.Options) > 0 {
+			c := policy.Conditional{Key: "options", List: true}
+
+			for _, o := range r.Options {
+				c.Values = append(c.Values, string(o))
+			}
+
+			rule.Conds = append(rule.Conds, c)
+		}
+
+		if r.Source != "" {
+			rule.Args = []string{r.Source}
+		} else if r.MountPoint != "" {
+			// the mount point follows ->, which requires a source
+			rule.Args = []string{"**"}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func (q RuleQualifiers) qualifiers() policy.Qualifiers {
+	return policy.Qualifiers{Deny: q.Deny, Audit: q.Audit}
+}
+
+// text returns the rendered structured rules, one per line
+func (s RuleSet) text() string {
+	lines := []string{}
+
+	for _, r := range s.PolicyRules() {
+		lines = append(lines, r.String()+",")
+	}
+
+	return strings.Join(lines, "\n")
+}
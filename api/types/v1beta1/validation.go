@@ -0,0 +1,261 @@
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
+)
+
+var (
+	errNoRules = errors.New("one of rules, structured rules or profile must be set")
+
+	pathRegexp  = regexp.MustCompile(`^(/|@\{)\S*$`)
+	permsRegexp = regexp.MustCompile(`^[rwaxlkmiuUpPcCD]+$`)
+	wordRegexp  = regexp.MustCompile(`^[^\s,()"#]+$`)
+
+	objectNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+	capabilities = map[Capability]bool{}
+
+	// reservedNames are built-in profiles of the container runtimes and directories of the AppArmor policy
+	// directory, a profile named after one of them would replace it on the nodes
+	reservedNames = map[string]bool{
+		"unconfined":                true,
+		"docker-default":            true,
+		"cri-containerd.apparmor.d": true,
+		"crio-default":              true,
+		"containers-default":        true,
+		"abi":                       true,
+		"abstractions":              true,
+		"cache":                     true,
+		"disable":                   true,
+		"force-complain":            true,
+		"local":                     true,
+		"tunables":                  true,
+	}
+)
+
+func init() {
+	for _, c := range strings.Split("audit_control audit_read audit_write block_suspend bpf checkpoint_restore chown "+
+		"dac_override dac_read_search fowner fsetid ipc_lock ipc_owner kill lease linux_immutable mac_admin "+
+		"mac_override mknod net_admin net_bind_service net_broadcast net_raw perfmon setfcap setgid setpcap setuid "+
+		"sys_admin sys_boot sys_chroot sys_module sys_nice sys_pacct sys_ptrace sys_rawio sys_resource sys_time "+
+		"sys_tty_config syslog wake_alarm", " ") {
+		capabilities[Capability(c)] = true
+	}
+}
+
+// ValidateAppArmorProfile checks that a profile sets either rules or a complete profile text, and that
+// the profile text declares a profile named after the object, the name of the file on the nodes
+func ValidateAppArmorProfile(p *AppArmorProfile) error {
+	spec := p.Spec
+
+	if reservedNames[p.Name] {
+		return fmt.Errorf("name %q is reserved, it collides with a built-in profile or policy directory on the nodes", p.Name)
+	}
+
+	for _, f := range spec.Fragments {
+		if !objectNameRegexp.MatchString(string(f)) {
+			return fmt.Errorf("invalid fragment name %q", f)
+		}
+	}
+
+	if spec.Profile == "" {
+		if strings.TrimSpace(spec.Rules) == "" && spec.RuleSet.IsEmpty() {
+			return errNoRules
+		}
+
+		_, err := policy.ParseRules(spec.Rules)
+
+		if err != nil {
+			return fmt.Errorf("invalid rules: %v", err)
+		}
+
+		return validateRuleSet(spec.RuleSet)
+	}
+
+	if spec.Rules != "" {
+		return fmt.Errorf("rules and profile are mutually exclusive")
+	}
+
+	if spec.Flags != nil || spec.Attachment != "" || spec.ABI != "" || len(spec.Includes) > 0 || len(spec.Fragments) > 0 || !spec.RuleSet.IsEmpty() {
+		return fmt.Errorf("flags, attachment, abi, includes, fragments and structured rules must be written in the profile text")
+	}
+
+	pol, err := policy.Parse(spec.Profile)
+
+	if err != nil {
+		return fmt.Errorf("invalid profile: %v", err)
+	}
+
+	for _, profile := range pol.Profiles() {
+		if reservedNames[profile.Name] {
+			return fmt.Errorf("invalid profile: line %s: profile name %q is reserved, it collides with a built-in profile", profile.Pos(), profile.Name)
+		}
+	}
+
+	profile := pol.Profile(p.Name)
+
+	if profile == nil {
+		return fmt.Errorf("profile text doesn't declare a profile named %q", p.Name)
+	}
+
+	switch spec.GetMode() {
+	case ModeKill, ModeUnconfined:
+		if profile.HasFlag(string(spec.GetMode())) {
+			return nil
+		}
+
+		return fmt.Errorf("mode %s must be set in the flags of profile %q", spec.GetMode(), p.Name)
+	}
+
+	return nil
+}
+
+// ValidateV1alpha1AppArmorProfile checks a profile converted from v1alpha1 like ValidateAppArmorProfile, except
+// that it may have no rules as v1alpha1 always allowed, the profile denies everything
+func ValidateV1alpha1AppArmorProfile(p *AppArmorProfile) error {
+	err := ValidateAppArmorProfile(p)
+
+	if err == errNoRules {
+		return nil
+	}
+
+	return err
+}
+
+// ValidateAppArmorProfileFragment checks that a fragment sets rules and that they parse
+func ValidateAppArmorProfileFragment(f *AppArmorProfileFragment) error {
+	if strings.TrimSpace(f.Spec.Rules) == "" && f.Spec.RuleSet.IsEmpty() {
+		return fmt.Errorf("one of rules or structured rules must be set")
+	}
+
+	_, err := policy.ParseRules(f.Spec.Rules)
+
+	if err != nil {
+		return fmt.Errorf("invalid rules: %v", err)
+	}
+
+	return validateRuleSet(f.Spec.RuleSet)
+}
+
+// ValidateAppArmorProfileBinding checks the selectors, the container name patterns and the profile names of a binding
+func ValidateAppArmorProfileBinding(b *AppArmorProfileBinding) error {
+	if len(b.Spec.Containers) == 0 {
+		return fmt.Errorf("containers must be set")
+	}
+
+	for _, selector := range []*metav1.LabelSelector{b.Spec.NamespaceSelector, b.Spec.PodSelector} {
+		_, err := metav1.LabelSelectorAsSelector(selector)
+
+		if err != nil {
+			return fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+
+	for _, c := range b.Spec.Containers {
+		_, err := path.Match(c.Name, "")
+
+		if err != nil {
+			return fmt.Errorf("invalid container name pattern %q: %v", c.Name, err)
+		}
+
+		if !objectNameRegexp.MatchString(c.Profile) {
+			return fmt.Errorf("invalid profile name %q", c.Profile)
+		}
+	}
+
+	return nil
+}
+
+// validateRuleSet checks the structured rules the way the CRD schema does, objects read from local
+// files aren't checked by the API server
+func validateRuleSet(spec RuleSet) error {
+	for _, r := range spec.Files {
+		if !pathRegexp.MatchString(r.Path) {
+			return fmt.Errorf("invalid file rule path %q", r.Path)
+		}
+
+		if !permsRegexp.MatchString(r.Permissions) {
+			return fmt.Errorf("invalid file rule permissions %q of %s", r.Permissions, r.Path)
+		}
+	}
+
+	for _, r := range spec.Capabilities {
+		if len(r.Names) == 0 {
+			return fmt.Errorf("capability rule without names")
+		}
+
+		for _, n := range r.Names {
+			if !capabilities[n] {
+				return fmt.Errorf("unknown capability %q", n)
+			}
+		}
+	}
+
+	for _, r := range spec.Network {
+		if err := validateWords("network family", r.Family); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range spec.Signal {
+		if err := validateWords("signal peer", string(r.Peer)); err != nil {
+			return err
+		}
+
+		for _, sig := range r.Signals {
+			if err := validateWords("signal", string(sig)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range spec.Ptrace {
+		if err := validateWords("ptrace peer", string(r.Peer)); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range spec.Mount {
+		if r.MountPoint != "" && !pathRegexp.MatchString(r.MountPoint) {
+			return fmt.Errorf("invalid mount point %q", r.MountPoint)
+		}
+
+		if err := validateWords("mount fstype or source", r.FSType, r.Source); err != nil {
+			return err
+		}
+
+		for _, o := range r.Options {
+			if err := validateWords("mount option", string(o)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the rendered rules must parse, e.g. a peer can't contain a space
+	_, err := policy.ParseRules(spec.text())
+
+	if err != nil {
+		return fmt.Errorf("invalid structured rules: %v", err)
+	}
+
+	return nil
+}
+
+// validateWords checks that the non empty values are single words of the policy language
+func validateWords(what string, values ...string) error {
+	for _, v := range values {
+		if v != "" && !wordRegexp.MatchString(v) {
+			return fmt.Errorf("invalid %s %q", what, v)
+		}
+	}
+
+	return nil
+}
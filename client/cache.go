@@ -0,0 +1,78 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	aaBetaClientset "github.com/sysdiglabs/kube-apparmor-manager/clientset/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// cacheSyncTimeout is the time to wait for the caches to be filled before giving up
+const cacheSyncTimeout = time.Minute
+
+// K8sCache serves the AppArmorProfile objects and the nodes of the cluster from informer caches, which are
+// kept up to date by watches. The objects it returns are shared with the caches and must not be modified.
+type K8sCache struct {
+	profiles aaBetaClientset.AppArmorProfileLister
+	nodes    corelisters.NodeLister
+}
+
+// NewCache starts the informers of the v1beta1 AppArmorProfile objects and the nodes until stopCh is closed,
+// it waits for their caches to be filled
+func (c *K8sClient) NewCache(stopCh <-chan struct{}) (*K8sCache, error) {
+	profileFactory := aaBetaClientset.NewSharedInformerFactory(c.aaBetaClient, 0)
+	nodeFactory := informers.NewSharedInformerFactory(c.cs, 0)
+
+	cache := &K8sCache{
+		profiles: profileFactory.AppArmorProfiles().Lister(),
+		nodes:    nodeFactory.Core().V1().Nodes().Lister(),
+	}
+
+	profileFactory.Start(stopCh)
+	nodeFactory.Start(stopCh)
+
+	timeout := make(chan struct{})
+	timer := time.AfterFunc(cacheSyncTimeout, func() { close(timeout) })
+	defer timer.Stop()
+
+	for name, synced := range profileFactory.WaitForCacheSync(timeout) {
+		if !synced {
+			return nil, fmt.Errorf("failed to fill the cache of %s", name)
+		}
+	}
+
+	for informer, synced := range nodeFactory.WaitForCacheSync(timeout) {
+		if !synced {
+			return nil, fmt.Errorf("failed to fill the cache of %v", informer)
+		}
+	}
+
+	return cache, nil
+}
+
+// GetAppArmorProfileObject returns a v1beta1 AppArmorProfile object from the cache
+func (c *K8sCache) GetAppArmorProfileObject(name string) (*v1beta1.AppArmorProfile, error) {
+	return c.profiles.Get(name)
+}
+
+// ListNodes returns the nodes of the cluster from the cache
+func (c *K8sCache) ListNodes() ([]corev1.Node, error) {
+	list, err := c.nodes.List(labels.Everything())
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []corev1.Node{}
+
+	for _, node := range list {
+		nodes = append(nodes, *node)
+	}
+
+	return nodes, nil
+}
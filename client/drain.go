@@ -0,0 +1,182 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+	drainPollInterval = 5 * time.Second
+)
+
+// SetUnschedulable cordons or uncordons a node, it returns whether the node was unschedulable before
+func (c *K8sClient) SetUnschedulable(name string, unschedulable bool) (bool, error) {
+	node, err := c.cs.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+
+	if err != nil {
+		return false, err
+	}
+
+	before := node.Spec.Unschedulable
+
+	if before == unschedulable {
+		return before, nil
+	}
+
+	node.Spec.Unschedulable = unschedulable
+
+	_, err = c.cs.CoreV1().Nodes().Update(node)
+
+	return before, err
+}
+
+// Drain evicts the pods running on a node through the eviction API, so that pod disruption budgets are respected.
+// DaemonSet pods, mirror pods and completed pods are left on the node.
+func (c *K8sClient) Drain(name string, timeout time.Duration) error {
+	list, err := c.cs.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	pods := []corev1.Pod{}
+
+	for _, pod := range list.Items {
+		if evictable(pod) {
+			pods = append(pods, pod)
+		}
+	}
+
+	klog.Infof("Evicting %d pods from node: %s", len(pods), name)
+
+	deadline := time.Now().Add(timeout)
+
+	for _, pod := range pods {
+		err := c.evict(pod, deadline)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, pod := range pods {
+		err := c.waitForDeletion(pod, deadline)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evict evicts a pod, evictions refused because of a pod disruption budget are retried until the deadline
+func (c *K8sClient) evict(pod corev1.Pod, deadline time.Time) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	for {
+		err := c.cs.CoreV1().Pods(pod.Namespace).Evict(eviction)
+
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return nil
+		case apierrors.IsTooManyRequests(err):
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timed out evicting pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+
+			klog.V(2).Infof("Eviction of pod %s/%s refused, retrying: %v", pod.Namespace, pod.Name, err)
+			time.Sleep(drainPollInterval)
+		default:
+			return fmt.Errorf("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+}
+
+func (c *K8sClient) waitForDeletion(pod corev1.Pod, deadline time.Time) error {
+	err := wait.PollImmediate(drainPollInterval, time.Until(deadline), func() (bool, error) {
+		p, err := c.cs.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		// a pod with the same name was created again by its controller
+		return p.UID != pod.UID, nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("timed out waiting for pod %s/%s to be deleted: %v", pod.Namespace, pod.Name, err)
+	}
+
+	return nil
+}
+
+// evictable checks whether a pod has to be evicted when draining its node
+func evictable(pod corev1.Pod) bool {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WaitForNodeReboot waits until a node is Ready with a boot id different from the given one
+func (c *K8sClient) WaitForNodeReboot(name, bootID string, timeout time.Duration) error {
+	return wait.PollImmediate(drainPollInterval, timeout, func() (bool, error) {
+		node, err := c.cs.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+
+		if err != nil {
+			// the API server may be unavailable while the node restarts
+			klog.V(2).Infof("Failed to get node %s: %v", name, err)
+			return false, nil
+		}
+
+		if node.Status.NodeInfo.BootID == bootID {
+			return false, nil
+		}
+
+		return nodeReady(node), nil
+	})
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
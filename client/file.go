@@ -0,0 +1,305 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// LoadAppArmorProfiles returns the apparmor profiles defined in local YAML or JSON files,
+// directories are walked and documents of other kinds are ignored
+func LoadAppArmorProfiles(paths []string) ([]types.AppArmorProfile, error) {
+	profileList := []types.AppArmorProfile{}
+
+	err := forEachDocument(paths, func(raw json.RawMessage) error {
+		p, legacy, err := decodeAppArmorProfile(raw)
+
+		if err != nil || p == nil {
+			return err
+		}
+
+		profile, err := newAppArmorProfile(*p, legacy)
+
+		if err != nil {
+			return err
+		}
+
+		profileList = append(profileList, profile)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return profileList, nil
+}
+
+// LoadAppArmorProfileFragments returns the profile fragments defined in local YAML or JSON files,
+// directories are walked and documents of other kinds are ignored
+func LoadAppArmorProfileFragments(paths []string) ([]types.AppArmorProfileFragment, error) {
+	fragmentList := []types.AppArmorProfileFragment{}
+
+	err := forEachDocument(paths, func(raw json.RawMessage) error {
+		meta := metav1.TypeMeta{}
+
+		err := json.Unmarshal(raw, &meta)
+
+		if err != nil {
+			return err
+		}
+
+		if meta.Kind != v1beta1.FragmentKind {
+			return nil
+		}
+
+		if meta.APIVersion != v1beta1.SchemeGroupVersion.String() {
+			return fmt.Errorf("unsupported apiVersion %q of %s", meta.APIVersion, meta.Kind)
+		}
+
+		f := v1beta1.AppArmorProfileFragment{}
+
+		err = json.Unmarshal(raw, &f)
+
+		if err != nil {
+			return err
+		}
+
+		fragment, err := newAppArmorProfileFragment(f)
+
+		if err != nil {
+			return err
+		}
+
+		fragmentList = append(fragmentList, fragment)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fragmentList, nil
+}
+
+// ProfileObjects are AppArmorProfile and AppArmorProfileFragment objects as they are written, before validation
+type ProfileObjects struct {
+	Profiles  []v1beta1.AppArmorProfile
+	Fragments []v1beta1.AppArmorProfileFragment
+	// Sources are the files the objects are read from indexed by kind and name, none for the cluster
+	Sources map[string]string
+	// V1alpha1 tells that the profiles are converted from v1alpha1 objects, the cluster doesn't serve v1beta1
+	V1alpha1 bool
+	// v1alpha1Profiles are the profiles converted from v1alpha1 documents of the files
+	v1alpha1Profiles map[string]bool
+}
+
+// IsV1alpha1 tells whether a profile is converted from a v1alpha1 object, which may have no rules
+func (o *ProfileObjects) IsV1alpha1(name string) bool {
+	return o.V1alpha1 || o.v1alpha1Profiles[name]
+}
+
+// Source returns the file an object is read from, empty if it comes from the cluster
+func (o *ProfileObjects) Source(kind, name string) string {
+	return o.Sources[kind+"/"+name]
+}
+
+// LoadProfileObjects returns the AppArmorProfile and AppArmorProfileFragment objects defined in local YAML or
+// JSON files without validating them, directories are walked and documents of other kinds are ignored
+func LoadProfileObjects(paths []string) (*ProfileObjects, error) {
+	objects := &ProfileObjects{Sources: map[string]string{}, v1alpha1Profiles: map[string]bool{}}
+
+	for _, path := range paths {
+		files, err := ManifestFiles(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			err := forEachFileDocument(file, func(raw json.RawMessage) error {
+				return objects.add(file, raw)
+			})
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s: %v", file, err)
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+func (o *ProfileObjects) add(file string, raw json.RawMessage) error {
+	p, legacy, err := decodeAppArmorProfile(raw)
+
+	if err != nil {
+		return err
+	}
+
+	if p != nil {
+		o.Profiles = append(o.Profiles, *p)
+		o.Sources[v1alpha1.Kind+"/"+p.Name] = file
+		o.v1alpha1Profiles[p.Name] = legacy
+		return nil
+	}
+
+	meta := metav1.TypeMeta{}
+
+	err = json.Unmarshal(raw, &meta)
+
+	if err != nil || meta.Kind != v1beta1.FragmentKind {
+		return err
+	}
+
+	f := v1beta1.AppArmorProfileFragment{}
+
+	err = json.Unmarshal(raw, &f)
+
+	if err != nil {
+		return err
+	}
+
+	o.Fragments = append(o.Fragments, f)
+	o.Sources[v1beta1.FragmentKind+"/"+f.Name] = file
+
+	return nil
+}
+
+// forEachDocument calls fn with every document of the YAML and JSON files under paths
+func forEachDocument(paths []string, fn func(raw json.RawMessage) error) error {
+	for _, path := range paths {
+		files, err := ManifestFiles(path)
+
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			err := forEachFileDocument(file, fn)
+
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %v", file, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func forEachFileDocument(file string, fn func(raw json.RawMessage) error) error {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	for {
+		raw := json.RawMessage{}
+
+		err := decoder.Decode(&raw)
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = fn(raw)
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decodeAppArmorProfile decodes a v1alpha1 or v1beta1 AppArmorProfile document into a v1beta1 profile and tells
+// whether it is converted from v1alpha1, nil is returned for documents of other kinds
+func decodeAppArmorProfile(raw []byte) (*v1beta1.AppArmorProfile, bool, error) {
+	meta := metav1.TypeMeta{}
+
+	err := json.Unmarshal(raw, &meta)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if meta.Kind != v1alpha1.Kind {
+		return nil, false, nil
+	}
+
+	p := &v1beta1.AppArmorProfile{}
+	legacy := false
+
+	switch meta.APIVersion {
+	case v1beta1.SchemeGroupVersion.String():
+		err = json.Unmarshal(raw, p)
+	case v1alpha1.SchemeGroupVersion.String():
+		in := &v1alpha1.AppArmorProfile{}
+
+		err = json.Unmarshal(raw, in)
+
+		if err == nil {
+			err = v1beta1.ConvertFromV1alpha1(in, p)
+		}
+
+		legacy = true
+	default:
+		return nil, false, fmt.Errorf("unsupported apiVersion %q of %s", meta.APIVersion, meta.Kind)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return p, legacy, nil
+}
+
+// ManifestFiles returns the YAML and JSON files under path, or path itself if it is a file
+func ManifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch filepath.Ext(file) {
+		case ".yaml", ".yml", ".json":
+			if !info.IsDir() {
+				files = append(files, file)
+			}
+		}
+
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
+)
+
+var (
+	documentSeparatorRegexp = regexp.MustCompile(`^---(\s|$)`)
+	specRegexp              = regexp.MustCompile(`^spec:\s*(#.*)?$`)
+	blockScalarRegexp       = regexp.MustCompile(`^(\s+)([a-z]+):\s*\|[-+0-9]*\s*(#.*)?$`)
+)
+
+// formatDocument has the fields of a document the formatter reads
+type formatDocument struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec map[string]interface{} `yaml:"spec"`
+}
+
+// Formatting is the result of formatting a manifest
+type Formatting struct {
+	// Changed are the fields which were not in the canonical format, e.g. AppArmorProfile/foo rules
+	Changed []string
+	// Warnings are the fields which can't be formatted and why
+	Warnings []string
+}
+
+// formatters render the text fields of the AppArmorProfile and AppArmorProfileFragment objects in the canonical format
+var formatters = map[string]func(string) (string, error){
+	"rules":   policy.FormatRules,
+	"profile": policy.FormatPolicy,
+}
+
+// FormatManifest formats the rules and profile texts of the AppArmorProfile and AppArmorProfileFragment documents of a
+// YAML manifest, the rest of the manifest is kept as it is. Only the literal block scalars are rewritten, e.g. rules: |
+func FormatManifest(data []byte) ([]byte, Formatting, error) {
+	formatting := Formatting{}
+	lines := strings.Split(string(data), "\n")
+	out := []string{}
+	start := 0
+
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !documentSeparatorRegexp.MatchString(lines[i]) {
+			continue
+		}
+
+		doc, err := formatDocumentLines(lines[start:i], &formatting)
+
+		if err != nil {
+			return nil, formatting, err
+		}
+
+		out = append(out, doc...)
+
+		if i < len(lines) {
+			out = append(out, lines[i])
+		}
+
+		start = i + 1
+	}
+
+	return []byte(strings.Join(out, "\n")), formatting, nil
+}
+
+// formatDocumentLines returns the lines of a document with its text fields formatted
+func formatDocumentLines(lines []string, formatting *Formatting) ([]string, error) {
+	doc := formatDocument{}
+
+	err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != v1alpha1.Kind && doc.Kind != v1beta1.FragmentKind {
+		return lines, nil
+	}
+
+	object := doc.Kind + "/" + doc.Metadata.Name
+
+	for _, field := range []string{"rules", "profile"} {
+		value, _ := doc.Spec[field].(string)
+
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		formatted, err := formatters[field](value)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s has invalid %s: %v", object, field, err)
+		}
+
+		formatted = indentWithSpaces(formatted)
+
+		if strings.TrimRight(formatted, "\n") == strings.TrimRight(value, "\n") {
+			continue
+		}
+
+		replaced, ok := replaceBlockScalar(lines, field, formatted)
+
+		if !ok {
+			formatting.Warnings = append(formatting.Warnings, fmt.Sprintf("%s %s is not a literal block scalar, it is left unchanged", object, field))
+			continue
+		}
+
+		// the rewritten document must hold the formatted text, anything else is a bug of the line editing
+		check := formatDocument{}
+		err = yaml.Unmarshal([]byte(strings.Join(replaced, "\n")), &check)
+		result, _ := check.Spec[field].(string)
+
+		if err != nil || strings.TrimRight(result, "\n") != strings.TrimRight(formatted, "\n") {
+			return nil, fmt.Errorf("failed to rewrite %s of %s", field, object)
+		}
+
+		lines = replaced
+		formatting.Changed = append(formatting.Changed, object+" "+field)
+	}
+
+	return lines, nil
+}
+
+// replaceBlockScalar replaces the content of the literal block scalar of a spec field, false if there is none
+func replaceBlockScalar(lines []string, field, text string) ([]string, bool) {
+	spec := -1
+
+	for i, line := range lines {
+		if specRegexp.MatchString(line) {
+			spec = i
+			break
+		}
+	}
+
+	if spec < 0 {
+		return nil, false
+	}
+
+	// the fields of the spec have the indentation of the first one
+	childIndent := ""
+
+	for i := spec + 1; i < len(lines); i++ {
+		line := lines[i]
+
+		if isBlankOrComment(line) {
+			continue
+		}
+
+		indent := leadingSpaces(line)
+
+		if indent == "" {
+			break
+		}
+
+		if childIndent == "" {
+			childIndent = indent
+		}
+
+		m := blockScalarRegexp.FindStringSubmatch(line)
+
+		if m == nil || m[1] != childIndent || m[2] != field {
+			continue
+		}
+
+		// the content are the lines indented more than the key, the trailing blank lines are kept after it
+		end := i + 1
+		contentIndent := ""
+
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+
+			indent := leadingSpaces(lines[j])
+
+			if len(indent) <= len(childIndent) {
+				break
+			}
+
+			if contentIndent == "" {
+				contentIndent = indent
+			}
+
+			end = j + 1
+		}
+
+		if contentIndent == "" {
+			contentIndent = childIndent + "  "
+		}
+
+		content := []string{}
+
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			if line == "" {
+				content = append(content, "")
+			} else {
+				content = append(content, contentIndent+line)
+			}
+		}
+
+		replaced := append(append(append([]string{}, lines[:i+1]...), content...), lines[end:]...)
+
+		return replaced, true
+	}
+
+	return nil, false
+}
+
+// indentWithSpaces replaces the tabs the nested blocks are indented with by two spaces, as the rest of a manifest
+func indentWithSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, "\t")
+		lines[i] = strings.Repeat("  ", len(line)-len(trimmed)) + trimmed
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+func leadingSpaces(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " "))]
+}
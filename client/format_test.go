@@ -0,0 +1,97 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		want     string
+		changed  []string
+		warnings []string
+		err      string
+	}{
+		{
+			name:    "rules rewritten, the other fields kept",
+			src:     "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: |\n    /tmp/** rw,\n    /etc/** r,\n  mode: enforce\n",
+			want:    "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: |\n    /etc/** r,\n    /tmp/** rw,\n  mode: enforce\n",
+			changed: []string{"AppArmorProfile/a rules"},
+		},
+		{
+			name:    "four spaces indentation, chomping indicator and comment",
+			src:     "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n    rules: |-  # the rules\n        /b r,\n        /a r,\n\n    mode: enforce\n",
+			want:    "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n    rules: |-  # the rules\n        /a r,\n        /b r,\n\n    mode: enforce\n",
+			changed: []string{"AppArmorProfile/a rules"},
+		},
+		{
+			name:    "blank lines inside and after the block, other documents untouched",
+			src:     "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: |\n    /a r,\n\n    /c r,\n    /b r,\n\n\n---\nkind: ConfigMap\nmetadata:\n  name: x\ndata:\n  rules: |\n    /b r,\n    /a r,\n",
+			want:    "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: |\n    /a r,\n\n    /b r,\n    /c r,\n\n\n---\nkind: ConfigMap\nmetadata:\n  name: x\ndata:\n  rules: |\n    /b r,\n    /a r,\n",
+			changed: []string{"AppArmorProfile/a rules"},
+		},
+		{
+			name:    "nested blocks of a profile indented with spaces",
+			src:     "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  profile: |\n    profile a {\n      /z r,\n      /a r,\n    }\n",
+			want:    "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  profile: |\n    profile a {\n      /a r,\n      /z r,\n    }\n",
+			changed: []string{"AppArmorProfile/a profile"},
+		},
+		{
+			name: "formatted fragment unchanged",
+			src:  "kind: AppArmorProfileFragment\nmetadata:\n  name: f\nspec:\n  rules: |\n    /a r,\n",
+			want: "kind: AppArmorProfileFragment\nmetadata:\n  name: f\nspec:\n  rules: |\n    /a r,\n",
+		},
+		{
+			name:     "quoted scalar left with a warning",
+			src:      "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: \"/b r,\\n/a r,\\n\"\n",
+			want:     "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: \"/b r,\\n/a r,\\n\"\n",
+			warnings: []string{"AppArmorProfile/a rules is not a literal block scalar, it is left unchanged"},
+		},
+		{
+			name: "invalid rules",
+			src:  "kind: AppArmorProfile\nmetadata:\n  name: a\nspec:\n  rules: |\n    /a rz,\n",
+			err:  `AppArmorProfile/a has invalid rules: line 1:4: unknown permissions "rz" of /a`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, formatting, err := FormatManifest([]byte(tt.src))
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+
+			if len(formatting.Changed) > 0 || len(tt.changed) > 0 {
+				if !reflect.DeepEqual(formatting.Changed, tt.changed) {
+					t.Errorf("expected changed %q, got %q", tt.changed, formatting.Changed)
+				}
+			}
+
+			if len(formatting.Warnings) > 0 || len(tt.warnings) > 0 {
+				if !reflect.DeepEqual(formatting.Warnings, tt.warnings) {
+					t.Errorf("expected warnings %q, got %q", tt.warnings, formatting.Warnings)
+				}
+			}
+
+			again, formatting, err := FormatManifest(got)
+
+			if err != nil || string(again) != string(got) || len(formatting.Changed) > 0 {
+				t.Errorf("formatting is not idempotent: %q, %v", again, err)
+			}
+		})
+	}
+}
@@ -1,36 +1,48 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"os"
 	"path/filepath"
-	"strings"
+	"reflect"
 	"time"
 
-	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 
 	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
 	aaClientset "github.com/sysdiglabs/kube-apparmor-manager/clientset/v1alpha1"
+	aaBetaClientset "github.com/sysdiglabs/kube-apparmor-manager/clientset/v1beta1"
+	crds "github.com/sysdiglabs/kube-apparmor-manager/crd"
 	"github.com/sysdiglabs/kube-apparmor-manager/types"
 	"github.com/sysdiglabs/kube-apparmor-manager/utils"
+	"github.com/sysdiglabs/kube-apparmor-manager/workload"
 	corev1 "k8s.io/api/core/v1"
 	extClientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 )
 
 type K8sClient struct {
-	cs        *kubernetes.Clientset
-	aaclient  *aaClientset.AppArmorV1Alpha1Client
-	extclient *extClientset.Clientset
+	cs           *kubernetes.Clientset
+	aaclient     *aaClientset.AppArmorV1Alpha1Client
+	aaBetaClient *aaBetaClientset.AppArmorV1Beta1Client
+	extclient    *extClientset.Clientset
 }
 
 // NewK8sClient return s Kubernetes client that contains the following
 // - cs: general k8s client
 // - aaclient: specific client to manage AppArmorProfile CRD object
+// - aaBetaClient: specific client to manage v1beta1 AppArmorProfile CRD object
 // - extclient: extension client to manage CRD
 func NewK8sClient() (*K8sClient, error) {
 	var kubeconfig *string
@@ -42,6 +54,12 @@ func NewK8sClient() (*K8sClient, error) {
 	flag.Parse()
 
 	v1alpha1.AddToScheme(scheme.Scheme)
+	v1beta1.AddToScheme(scheme.Scheme)
+
+	// the webhook runs in the cluster without a kubeconfig, the service account is used instead
+	if _, err := os.Stat(*kubeconfig); err != nil {
+		*kubeconfig = ""
+	}
 
 	// use the current context in kubeconfig
 	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
@@ -59,6 +77,11 @@ func NewK8sClient() (*K8sClient, error) {
 		return nil, err
 	}
 
+	aaBetaClientset, err := aaBetaClientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	extClient, err := extClientset.NewForConfig(config)
 
 	if err != nil {
@@ -68,61 +91,189 @@ func NewK8sClient() (*K8sClient, error) {
 	return &K8sClient{
 		clientset,
 		aaClientset,
+		aaBetaClientset,
 		extClient,
 	}, nil
 }
 
-// InstallCRD installs AppArmorProfile CRD
-func (c *K8sClient) InstallCRD() error {
-	klog.Infof("Creating a CRD: %s\n", v1alpha1.Name)
+// InstallCRD installs AppArmorProfile, AppArmorProfileFragment and AppArmorProfileBinding CRDs, an installed CRD created from an older
+// manifest is updated in place. v1beta1 profiles are only served if the conversion webhook is configured.
+func (c *K8sClient) InstallCRD(webhook *crds.WebhookConfig) error {
+	crd, err := crds.AppArmorProfile(webhook)
 
-	crd := &apiextensions.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: v1alpha1.Name,
-		},
-		Spec: apiextensions.CustomResourceDefinitionSpec{
-			Group: v1alpha1.GroupName,
-			Versions: []apiextensions.CustomResourceDefinitionVersion{
-				{Name: v1alpha1.GroupVersion, Served: true, Storage: true},
-			},
-			Scope: apiextensions.ClusterScoped,
-			Names: apiextensions.CustomResourceDefinitionNames{
-				Plural:     v1alpha1.Plural,
-				Kind:       v1alpha1.Kind,
-				ListKind:   v1alpha1.ListKind,
-				ShortNames: []string{"aap"},
-			},
-		},
+	if err != nil {
+		return err
 	}
 
-	_, err := c.extclient.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
+	err = c.installCRD(crd)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			return nil
-		}
 		return err
 	}
 
-	klog.Infoln("The CRD created. Need to wait whether it is confirmed.")
+	for _, get := range []func() (*apiextensions.CustomResourceDefinition, error){crds.AppArmorProfileFragment, crds.AppArmorProfileBinding} {
+		crd, err = get()
+
+		if err != nil {
+			return err
+		}
+
+		err = c.installCRD(crd)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *K8sClient) installCRD(crd *apiextensions.CustomResourceDefinition) error {
+	client := c.extclient.ApiextensionsV1().CustomResourceDefinitions()
+
+	existing, err := client.Get(crd.Name, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		klog.Infof("Creating a CRD: %s\n", crd.Name)
+
+		_, err = client.Create(crd)
+
+		if err != nil {
+			return err
+		}
+
+		klog.Infoln("The CRD created. Need to wait whether it is confirmed.")
+	case err != nil:
+		return err
+	case existing.Annotations[crds.SpecHashAnnotation] == crd.Annotations[crds.SpecHashAnnotation]:
+		klog.Infof("The CRD is up to date: %s\n", crd.Name)
+		return nil
+	default:
+		klog.Infof("Updating the CRD: %s\n", crd.Name)
+
+		if existing.Annotations == nil {
+			existing.Annotations = map[string]string{}
+		}
+
+		for k, v := range crd.Annotations {
+			existing.Annotations[k] = v
+		}
+
+		existing.Spec = crd.Spec
+
+		_, err = client.Update(existing)
+
+		if err != nil {
+			return err
+		}
+	}
 
-	return c.waitForCRD()
+	return c.waitForCRD(crd.Name)
 }
 
-// RemoveCRD removes AppArmorProfile CRD
+// RemoveCRD removes AppArmorProfile, AppArmorProfileFragment and AppArmorProfileBinding CRDs, the objects are
+// deleted along with them
 func (c *K8sClient) RemoveCRD() error {
-	c.extclient.ApiextensionsV1beta1().RESTClient().Delete().Name(v1alpha1.Name)
+	for _, name := range []string{v1alpha1.Name, v1beta1.FragmentCRDName, v1beta1.BindingCRDName} {
+		klog.Infof("Deleting the CRD: %s\n", name)
+
+		err := c.extclient.ApiextensionsV1().CustomResourceDefinitions().Delete(name, &metav1.DeleteOptions{})
+
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InstallValidatingWebhook registers the validating webhook of AppArmorProfile and AppArmorProfileFragment objects
+func (c *K8sClient) InstallValidatingWebhook(webhook *crds.WebhookConfig) error {
+	client := c.cs.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	config := crds.ValidatingWebhook(webhook)
+
+	existing, err := client.Get(config.Name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		klog.Infof("Creating a validating webhook: %s\n", config.Name)
+
+		_, err = client.Create(config)
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Updating the validating webhook: %s\n", config.Name)
+
+	existing.Webhooks = config.Webhooks
+
+	_, err = client.Update(existing)
+
+	return err
+}
+
+// RemoveValidatingWebhook removes the validating webhook registration, objects are no longer validated on admission
+func (c *K8sClient) RemoveValidatingWebhook() error {
+	err := c.cs.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(crds.ValidatingWebhookName, &metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
 	return nil
 }
 
-func (c *K8sClient) waitForCRD() error {
-	klog.Infof("Waiting for a CRD to be created: %s\n", v1alpha1.Name)
+// InstallMutatingWebhook registers the webhook assigning the profiles of the AppArmorProfileBinding objects to pods
+func (c *K8sClient) InstallMutatingWebhook(webhook *crds.WebhookConfig) error {
+	client := c.cs.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	config := crds.MutatingWebhook(webhook)
+
+	existing, err := client.Get(config.Name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		klog.Infof("Creating a mutating webhook: %s\n", config.Name)
+
+		_, err = client.Create(config)
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Updating the mutating webhook: %s\n", config.Name)
+
+	existing.Webhooks = config.Webhooks
+
+	_, err = client.Update(existing)
+
+	return err
+}
+
+// RemoveMutatingWebhook removes the mutating webhook registration, profiles are no longer assigned to new pods
+func (c *K8sClient) RemoveMutatingWebhook() error {
+	err := c.cs.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(crds.MutatingWebhookName, &metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *K8sClient) waitForCRD(name string) error {
+	klog.Infof("Waiting for a CRD to be established: %s\n", name)
 
 	err := wait.Poll(1*time.Second, 30*time.Second, func() (bool, error) {
 		// get CRDs by name
-		crd, err := c.extclient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(v1alpha1.Name, metav1.GetOptions{})
+		crd, err := c.extclient.ApiextensionsV1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
 		if err != nil {
-			panic(err)
+			return false, err
 		}
 
 		for _, condition := range crd.Status.Conditions {
@@ -144,64 +295,430 @@ func (c *K8sClient) waitForCRD() error {
 	return nil
 }
 
-// GetNodes returns node list
-func (c *K8sClient) GetNodes() (types.NodeList, error) {
+// GetNodes returns node list, the label selector of the filter is applied server side
+func (c *K8sClient) GetNodes(filter types.NodeFilter) (types.NodeList, error) {
 	nodeList := types.NodeList{}
 
-	list, err := c.cs.CoreV1().Nodes().List(metav1.ListOptions{})
+	list, err := c.cs.CoreV1().Nodes().List(metav1.ListOptions{
+		LabelSelector: filter.Selector,
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	for _, node := range list.Items {
-		nodeReady := false
-		for _, condition := range node.Status.Conditions {
-			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
-				nodeReady = true
-				break
+		n := types.NewNode()
+		role := node.Labels[types.RoleLabel]
+		n.Role = role
+		n.NodeName = node.Name
+
+		for _, addr := range node.Status.Addresses {
+			n.AddAddress(string(addr.Type), addr.Address)
+		}
+
+		n.BootID = node.Status.NodeInfo.BootID
+		n.Source = types.SourceKubernetes
+
+		switch {
+		case !filter.Match(node.Name):
+			n.Skip(types.SkipExcluded)
+		case !nodeReady(&node) && !filter.IncludeSkipped:
+			n.Skip(types.SkipNotReady)
+		case node.Spec.Unschedulable && !filter.IncludeSkipped:
+			n.Skip(types.SkipCordoned)
+		}
+
+		nodeList = append(nodeList, n)
+	}
+
+	return nodeList, nil
+}
+
+// ListNodes returns the Kubernetes nodes as they are in the cluster
+func (c *K8sClient) ListNodes() ([]corev1.Node, error) {
+	list, err := c.cs.CoreV1().Nodes().List(metav1.ListOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// GetNamespace returns a namespace of the cluster
+func (c *K8sClient) GetNamespace(name string) (*corev1.Namespace, error) {
+	return c.cs.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+}
+
+// ListAppArmorProfileBindings returns the AppArmorProfileBinding objects, none if the binding CRD is not installed
+func (c *K8sClient) ListAppArmorProfileBindings() ([]v1beta1.AppArmorProfileBinding, error) {
+	list, err := c.aaBetaClient.ApparmorProfileBindings().List(context.TODO(), metav1.ListOptions{})
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// GetAppArmorProfileObject returns a v1beta1 AppArmorProfile object with its status
+func (c *K8sClient) GetAppArmorProfileObject(name string) (*v1beta1.AppArmorProfile, error) {
+	return c.aaBetaClient.ApparmorProfiles().Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// UpdateAppArmorProfileNodes updates the nodes a profile is loaded on in its status, update returns the new
+// nodes from the current ones. Nothing is done if v1beta1 is not served, v1alpha1 has no status.
+func (c *K8sClient) UpdateAppArmorProfileNodes(name string, update func(nodes []string) []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		p, err := c.aaBetaClient.ApparmorProfiles().Get(context.TODO(), name, metav1.GetOptions{})
+
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		nodes := update(p.Status.Nodes)
+
+		if reflect.DeepEqual(nodes, p.Status.Nodes) {
+			return nil
+		}
+
+		p.Status.Nodes = nodes
+
+		_, err = c.aaBetaClient.ApparmorProfiles().UpdateStatus(context.TODO(), p, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// AddAppArmorProfileFinalizer sets the finalizer keeping a profile object until its profile is removed from the nodes.
+// The v1alpha1 object is updated if v1beta1 is not served.
+func (c *K8sClient) AddAppArmorProfileFinalizer(name string) error {
+	return c.updateAppArmorProfileFinalizers(name, func(finalizers []string) []string {
+		for _, f := range finalizers {
+			if f == v1beta1.ProfileFinalizer {
+				return finalizers
 			}
 		}
 
-		if nodeReady {
-			n := types.NewNode()
-			role := node.Labels[types.RoleLabel]
-			n.Role = role
-			n.NodeName = node.Name
-
-			for _, addr := range node.Status.Addresses {
-				switch addr.Type {
-				case corev1.NodeExternalIP:
-					n.ExternalIP = addr.Address
-				case corev1.NodeInternalIP:
-					n.InternalIP = addr.Address
-				default:
-				}
+		return append(finalizers, v1beta1.ProfileFinalizer)
+	})
+}
+
+// RemoveAppArmorProfileFinalizer releases the finalizer of a profile object, a deleted object is gone afterwards
+func (c *K8sClient) RemoveAppArmorProfileFinalizer(name string) error {
+	return c.updateAppArmorProfileFinalizers(name, func(finalizers []string) []string {
+		kept := []string{}
+
+		for _, f := range finalizers {
+			if f != v1beta1.ProfileFinalizer {
+				kept = append(kept, f)
 			}
+		}
+
+		return kept
+	})
+}
+
+// RemoveAppArmorProfileFinalizers releases the finalizers of all the profile objects, e.g. before deleting the CRD
+func (c *K8sClient) RemoveAppArmorProfileFinalizers() error {
+	names := []string{}
+	list, err := c.aaBetaClient.ApparmorProfiles().List(context.TODO(), metav1.ListOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		alpha, err := c.aaclient.ApparmorProfiles().List(context.TODO(), metav1.ListOptions{})
+
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
 
-			nodeList = append(nodeList, n)
+		for _, p := range alpha.Items {
+			names = append(names, p.Name)
+		}
+	case err != nil:
+		return err
+	default:
+		for _, p := range list.Items {
+			names = append(names, p.Name)
 		}
 	}
 
-	return nodeList, nil
+	for _, name := range names {
+		err := c.RemoveAppArmorProfileFinalizer(name)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// GetAppArmorProfiles returns apparmor profiles from etcd
+// updateAppArmorProfileFinalizers updates the finalizers of a profile object through v1beta1, or v1alpha1 if v1beta1
+// is not served, nothing is done if the object doesn't exist
+func (c *K8sClient) updateAppArmorProfileFinalizers(name string, update func(finalizers []string) []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		p, err := c.aaBetaClient.ApparmorProfiles().Get(context.TODO(), name, metav1.GetOptions{})
+
+		if apierrors.IsNotFound(err) {
+			return c.updateV1alpha1AppArmorProfileFinalizers(name, update)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		finalizers := update(p.Finalizers)
+
+		if len(finalizers) == len(p.Finalizers) {
+			return nil
+		}
+
+		p.Finalizers = finalizers
+
+		_, err = c.aaBetaClient.ApparmorProfiles().Update(context.TODO(), p, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+func (c *K8sClient) updateV1alpha1AppArmorProfileFinalizers(name string, update func(finalizers []string) []string) error {
+	p, err := c.aaclient.ApparmorProfiles().Get(context.TODO(), name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	finalizers := update(p.Finalizers)
+
+	if len(finalizers) == len(p.Finalizers) {
+		return nil
+	}
+
+	p.Finalizers = finalizers
+
+	_, err = c.aaclient.ApparmorProfiles().Update(context.TODO(), p, metav1.UpdateOptions{})
+
+	return err
+}
+
+// ListPods returns the AppArmor profile fields of the pods of all the namespaces
+func (c *K8sClient) ListPods() ([]workload.Pod, error) {
+	raw, err := c.cs.CoreV1().RESTClient().Get().Resource("pods").Do().Raw()
+
+	if err != nil {
+		return nil, err
+	}
+
+	list := struct {
+		Items []workload.Pod `json:"items"`
+	}{}
+
+	err = json.Unmarshal(raw, &list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// GetAppArmorProfiles returns apparmor profiles from etcd, v1alpha1 is used if v1beta1 is not served. Invalid
+// objects are skipped with a warning, the objects being deleted aren't validated since they are only removed
+// from the nodes.
 func (c *K8sClient) GetAppArmorProfiles() ([]types.AppArmorProfile, error) {
 	profileList := []types.AppArmorProfile{}
-	list, err := c.aaclient.ApparmorProfiles().List(metav1.ListOptions{})
+	objects, err := c.GetProfileObjects()
 
 	if err != nil {
 		return profileList, err
 	}
 
-	for _, p := range list.Items {
-		var profile types.AppArmorProfile
-		profile.Name = p.Name
-		profile.Rules = p.Spec.Rules
-		profile.Enforced = p.Spec.Enforced
-		profileList = append(profileList, profile)
+	validate := profileValidator(objects.V1alpha1)
+
+	for _, p := range objects.Profiles {
+		if p.DeletionTimestamp == nil {
+			err := validate(&p)
+
+			if err != nil {
+				klog.Warningf("Skipping invalid profile %s: %v", p.Name, err)
+				continue
+			}
+		}
+
+		profileList = append(profileList, toAppArmorProfile(p))
 	}
 
 	return profileList, nil
 }
+
+// profileValidator returns the validation of profiles, the ones converted from v1alpha1 may have no rules
+func profileValidator(legacy bool) func(p *v1beta1.AppArmorProfile) error {
+	if legacy {
+		return v1beta1.ValidateV1alpha1AppArmorProfile
+	}
+
+	return v1beta1.ValidateAppArmorProfile
+}
+
+// newAppArmorProfile returns the profile to deploy on the nodes, invalid profiles are refused, legacy tells
+// that the profile is converted from v1alpha1
+func newAppArmorProfile(p v1beta1.AppArmorProfile, legacy bool) (types.AppArmorProfile, error) {
+	err := profileValidator(legacy)(&p)
+
+	if err != nil {
+		return types.AppArmorProfile{}, fmt.Errorf("invalid profile %s: %v", p.Name, err)
+	}
+
+	return toAppArmorProfile(p), nil
+}
+
+// toAppArmorProfile returns the profile of an object which is validated already
+func toAppArmorProfile(p v1beta1.AppArmorProfile) types.AppArmorProfile {
+	var profile types.AppArmorProfile
+
+	profile.Name = p.Name
+	profile.Deleted = p.DeletionTimestamp != nil
+	profile.Rules = p.Spec.Rules
+	profile.Raw = p.Spec.Profile
+	profile.Structured = p.Spec.PolicyRules()
+	profile.Mode = p.Spec.GetMode()
+	profile.Attachment = p.Spec.Attachment
+	profile.ABI = string(p.Spec.ABI)
+
+	for _, f := range p.Spec.GetFlags() {
+		profile.Flags = append(profile.Flags, string(f))
+	}
+
+	for _, include := range p.Spec.Includes {
+		profile.Includes = append(profile.Includes, string(include))
+	}
+
+	for _, fragment := range p.Spec.Fragments {
+		profile.Fragments = append(profile.Fragments, string(fragment))
+	}
+
+	return profile
+}
+
+// ListAppArmorProfileObjects returns the AppArmorProfile objects of the cluster, v1alpha1 objects are converted if
+// v1beta1 is not served
+func (c *K8sClient) ListAppArmorProfileObjects() ([]v1beta1.AppArmorProfile, error) {
+	objects, err := c.GetProfileObjects()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return objects.Profiles, nil
+}
+
+// GetProfileObjects returns the AppArmorProfile and AppArmorProfileFragment objects of the cluster, v1alpha1
+// profiles are converted if v1beta1 is not served
+func (c *K8sClient) GetProfileObjects() (*ProfileObjects, error) {
+	objects := &ProfileObjects{Sources: map[string]string{}}
+	list, err := c.aaBetaClient.ApparmorProfiles().List(context.TODO(), metav1.ListOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		alpha, err := c.aaclient.ApparmorProfiles().List(context.TODO(), metav1.ListOptions{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		objects.V1alpha1 = true
+
+		for i := range alpha.Items {
+			p := v1beta1.AppArmorProfile{}
+
+			err := v1beta1.ConvertFromV1alpha1(&alpha.Items[i], &p)
+
+			if err != nil {
+				klog.Warningf("Skipping profile %s which failed to convert: %v", alpha.Items[i].Name, err)
+				continue
+			}
+
+			objects.Profiles = append(objects.Profiles, p)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		objects.Profiles = list.Items
+	}
+
+	fragments, err := c.aaBetaClient.ApparmorProfileFragments().List(context.TODO(), metav1.ListOptions{})
+
+	if apierrors.IsNotFound(err) {
+		return objects, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	objects.Fragments = fragments.Items
+
+	return objects, nil
+}
+
+// GetAppArmorProfileFragments returns the profile fragments from etcd, none if the fragment CRD is not installed
+func (c *K8sClient) GetAppArmorProfileFragments() ([]types.AppArmorProfileFragment, error) {
+	fragmentList := []types.AppArmorProfileFragment{}
+	list, err := c.aaBetaClient.ApparmorProfileFragments().List(context.TODO(), metav1.ListOptions{})
+
+	if apierrors.IsNotFound(err) {
+		return fragmentList, nil
+	}
+
+	if err != nil {
+		return fragmentList, err
+	}
+
+	for _, f := range list.Items {
+		fragment, err := newAppArmorProfileFragment(f)
+
+		if err != nil {
+			klog.Warningf("Skipping %v", err)
+			continue
+		}
+
+		fragmentList = append(fragmentList, fragment)
+	}
+
+	return fragmentList, nil
+}
+
+// newAppArmorProfileFragment returns the fragment to deploy on the nodes, invalid fragments are refused
+func newAppArmorProfileFragment(f v1beta1.AppArmorProfileFragment) (types.AppArmorProfileFragment, error) {
+	var fragment types.AppArmorProfileFragment
+
+	err := v1beta1.ValidateAppArmorProfileFragment(&f)
+
+	if err != nil {
+		return fragment, fmt.Errorf("invalid fragment %s: %v", f.Name, err)
+	}
+
+	fragment.Name = f.Name
+	fragment.Rules = f.Spec.Rules
+	fragment.Structured = f.Spec.PolicyRules()
+
+	return fragment, nil
+}
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
 )
 
 type SSHClient struct {
-	config *ssh.ClientConfig
-	client *ssh.Client
+	config     *ssh.ClientConfig
+	client     *ssh.Client
+	passPhrase string
 }
 
 // NewSSHClientConfig returns client configuration for SSH client
@@ -31,13 +35,40 @@ func NewSSHClientConfig(user, keyFile, passworkPhrase string) (*SSHClient, error
 	}
 
 	return &SSHClient{
-		config: sshConfig,
+		config:     sshConfig,
+		passPhrase: passworkPhrase,
+	}, nil
+}
+
+// WithConfig returns a new client with the per node settings applied on top of the client configuration
+func (c *SSHClient) WithConfig(nodeConfig *types.SSHConfig) (*SSHClient, error) {
+	config := *c.config
+
+	if nodeConfig != nil {
+		if nodeConfig.User != "" {
+			config.User = nodeConfig.User
+		}
+
+		if nodeConfig.KeyFile != "" {
+			publicKeyMenthod, err := publicKey(nodeConfig.KeyFile, c.passPhrase)
+
+			if err != nil {
+				return nil, err
+			}
+
+			config.Auth = []ssh.AuthMethod{publicKeyMenthod}
+		}
+	}
+
+	return &SSHClient{
+		config:     &config,
+		passPhrase: c.passPhrase,
 	}, nil
 }
 
 // Connect connects to a node
 func (c *SSHClient) Connect(host, port string) error {
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%s", host, port), c.config)
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, port), c.config)
 
 	if err != nil {
 		return err
@@ -59,10 +90,20 @@ func (c *SSHClient) Close() error {
 
 // ExecuteBatch execute bach commands
 func (c *SSHClient) ExecuteBatch(commands []string, prependSudo bool) error {
+	return c.executeBatch(commands, prependSudo, false)
+}
+
+// ExecuteBatchChecked executes batch commands like ExecuteBatch, it stops at the first command exiting with
+// a non zero status and returns its error
+func (c *SSHClient) ExecuteBatchChecked(commands []string, prependSudo bool) error {
+	return c.executeBatch(commands, prependSudo, true)
+}
+
+func (c *SSHClient) executeBatch(commands []string, prependSudo, checked bool) error {
 	fmt.Printf("**** Host: %s ****\n", c.client.RemoteAddr().String())
 	for _, cmd := range commands {
 		fmt.Printf("** Execute command: %s **\n", cmd)
-		stdout, stderr, err := c.ExecuteOne(cmd, prependSudo)
+		stdout, stderr, status, err := c.execute(cmd, prependSudo)
 
 		if err != nil {
 			return err
@@ -76,17 +117,28 @@ func (c *SSHClient) ExecuteBatch(commands []string, prependSudo bool) error {
 			fmt.Printf("Error: %s\n", stderr)
 		}
 		fmt.Println()
+
+		if checked && status != nil {
+			return fmt.Errorf("command %q failed: %v: %s", cmd, status, stderr)
+		}
 	}
 
 	return nil
 }
 
-// ExecuteOne executes one command
+// ExecuteOne executes one command, the exit status is ignored
 func (c *SSHClient) ExecuteOne(cmd string, prependSudo bool) (stdout, stderr string, err error) {
+	stdout, stderr, _, err = c.execute(cmd, prependSudo)
+
+	return stdout, stderr, err
+}
+
+// execute executes one command, status is the error the command exited with, err the error running it
+func (c *SSHClient) execute(cmd string, prependSudo bool) (stdout, stderr string, status, err error) {
 	sess, err := c.client.NewSession()
 
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
 	defer sess.Close()
@@ -99,10 +151,9 @@ func (c *SSHClient) ExecuteOne(cmd string, prependSudo bool) (stdout, stderr str
 		cmd = "sudo " + cmd
 	}
 
-	_ = sess.Run(cmd)
-
-	return strings.TrimSuffix(stdoutBuf.String(), "\n"), strings.TrimSuffix(stderrBuf.String(), "\n"), nil
+	status = sess.Run(cmd)
 
+	return strings.TrimSuffix(stdoutBuf.String(), "\n"), strings.TrimSuffix(stderrBuf.String(), "\n"), status, nil
 }
 
 func publicKey(keyPath, passwordPhrase string) (ssh.AuthMethod, error) {
@@ -8,14 +8,19 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+// AppArmorV1Alpha1Interface is the client of the crd.security.sysdig.com/v1alpha1 group version
 type AppArmorV1Alpha1Interface interface {
-	AppArmorProfiles(namespace string) AppArmorProfileInterface
+	RESTClient() rest.Interface
+	ApparmorProfiles() AppArmorProfileInterface
 }
 
 type AppArmorV1Alpha1Client struct {
 	restClient rest.Interface
 }
 
+var _ AppArmorV1Alpha1Interface = &AppArmorV1Alpha1Client{}
+
+// NewForConfig creates a new client for the given config
 func NewForConfig(c *rest.Config) (*AppArmorV1Alpha1Client, error) {
 	config := *c
 	config.ContentConfig.GroupVersion = &schema.GroupVersion{Group: v1alpha1.GroupName, Version: v1alpha1.GroupVersion}
@@ -32,6 +37,17 @@ func NewForConfig(c *rest.Config) (*AppArmorV1Alpha1Client, error) {
 	return &AppArmorV1Alpha1Client{restClient: client}, nil
 }
 
+// New creates a new client for the given REST client
+func New(c rest.Interface) *AppArmorV1Alpha1Client {
+	return &AppArmorV1Alpha1Client{restClient: c}
+}
+
+// RESTClient returns the REST client used to communicate with the API server
+func (c *AppArmorV1Alpha1Client) RESTClient() rest.Interface {
+	return c.restClient
+}
+
+// ApparmorProfiles returns the client of the cluster scoped AppArmorProfile resources
 func (c *AppArmorV1Alpha1Client) ApparmorProfiles() AppArmorProfileInterface {
 	return &appArmorProfileClient{
 		restClient: c.restClient,
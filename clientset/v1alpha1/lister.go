@@ -0,0 +1,49 @@
+package v1alpha1
+
+import (
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// AppArmorProfileLister lists AppArmorProfile objects from an informer cache
+type AppArmorProfileLister interface {
+	// List lists all the profiles matching the selector
+	List(selector labels.Selector) ([]*v1alpha1.AppArmorProfile, error)
+	// Get returns the profile with the given name
+	Get(name string) (*v1alpha1.AppArmorProfile, error)
+}
+
+type appArmorProfileLister struct {
+	indexer cache.Indexer
+}
+
+// NewAppArmorProfileLister returns a new lister reading from the indexer of an informer
+func NewAppArmorProfileLister(indexer cache.Indexer) AppArmorProfileLister {
+	return &appArmorProfileLister{indexer: indexer}
+}
+
+func (l *appArmorProfileLister) List(selector labels.Selector) ([]*v1alpha1.AppArmorProfile, error) {
+	ret := []*v1alpha1.AppArmorProfile{}
+
+	err := cache.ListAll(l.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.AppArmorProfile))
+	})
+
+	return ret, err
+}
+
+func (l *appArmorProfileLister) Get(name string) (*v1alpha1.AppArmorProfile, error) {
+	obj, exists, err := l.indexer.GetByKey(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource(v1alpha1.Plural), name)
+	}
+
+	return obj.(*v1alpha1.AppArmorProfile), nil
+}
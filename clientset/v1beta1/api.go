@@ -0,0 +1,71 @@
+package v1beta1
+
+import (
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// AppArmorV1Beta1Interface is the client of the crd.security.sysdig.com/v1beta1 group version
+type AppArmorV1Beta1Interface interface {
+	RESTClient() rest.Interface
+	ApparmorProfiles() AppArmorProfileInterface
+	ApparmorProfileFragments() AppArmorProfileFragmentInterface
+	ApparmorProfileBindings() AppArmorProfileBindingInterface
+}
+
+type AppArmorV1Beta1Client struct {
+	restClient rest.Interface
+}
+
+var _ AppArmorV1Beta1Interface = &AppArmorV1Beta1Client{}
+
+// NewForConfig creates a new client for the given config
+func NewForConfig(c *rest.Config) (*AppArmorV1Beta1Client, error) {
+	config := *c
+	config.ContentConfig.GroupVersion = &schema.GroupVersion{Group: v1beta1.SchemeGroupVersion.Group, Version: v1beta1.GroupVersion}
+	config.APIPath = "/apis"
+	//config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	config.NegotiatedSerializer = serializer.WithoutConversionCodecFactory{CodecFactory: scheme.Codecs}
+	config.UserAgent = rest.DefaultKubernetesUserAgent()
+
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppArmorV1Beta1Client{restClient: client}, nil
+}
+
+// New creates a new client for the given REST client
+func New(c rest.Interface) *AppArmorV1Beta1Client {
+	return &AppArmorV1Beta1Client{restClient: c}
+}
+
+// RESTClient returns the REST client used to communicate with the API server
+func (c *AppArmorV1Beta1Client) RESTClient() rest.Interface {
+	return c.restClient
+}
+
+// ApparmorProfiles returns the client of the cluster scoped AppArmorProfile resources
+func (c *AppArmorV1Beta1Client) ApparmorProfiles() AppArmorProfileInterface {
+	return &appArmorProfileClient{
+		restClient: c.restClient,
+	}
+}
+
+// ApparmorProfileFragments returns the client of the cluster scoped AppArmorProfileFragment resources
+func (c *AppArmorV1Beta1Client) ApparmorProfileFragments() AppArmorProfileFragmentInterface {
+	return &appArmorProfileFragmentClient{
+		restClient: c.restClient,
+	}
+}
+
+// ApparmorProfileBindings returns the client of the cluster scoped AppArmorProfileBinding resources
+func (c *AppArmorV1Beta1Client) ApparmorProfileBindings() AppArmorProfileBindingInterface {
+	return &appArmorProfileBindingClient{
+		restClient: c.restClient,
+	}
+}
@@ -0,0 +1,181 @@
+package v1beta1
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	apparmorProfiles = "apparmorprofiles"
+)
+
+// AppArmorProfileInterface has methods to work with AppArmorProfile resources
+type AppArmorProfileInterface interface {
+	Create(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.CreateOptions) (*v1beta1.AppArmorProfile, error)
+	Update(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfile, error)
+	UpdateStatus(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfile, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfile, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfile, error)
+}
+
+type appArmorProfileClient struct {
+	restClient rest.Interface
+}
+
+// Get takes name of the profile, and returns the corresponding profile object, and an error if there is any
+func (c *appArmorProfileClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfile, error) {
+	result := v1beta1.AppArmorProfile{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		Name(name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// List takes label and field selectors, and returns the list of profiles that match those selectors
+func (c *appArmorProfileClient) List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	result := v1beta1.AppArmorProfileList{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Watch returns a watch.Interface that watches the requested profiles
+func (c *appArmorProfileClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	opts.Watch = true
+	return c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a profile and creates it, it returns the server's representation of the profile
+func (c *appArmorProfileClient) Create(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.CreateOptions) (*v1beta1.AppArmorProfile, error) {
+	result := v1beta1.AppArmorProfile{}
+	err := c.restClient.
+		Post().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(profile).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Update takes the representation of a profile and updates it, it returns the server's representation of the profile
+func (c *appArmorProfileClient) Update(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfile, error) {
+	result := v1beta1.AppArmorProfile{}
+	err := c.restClient.
+		Put().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		Name(profile.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(profile).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// UpdateStatus updates the status subresource of a profile, changes to the spec are ignored by the server
+func (c *appArmorProfileClient) UpdateStatus(ctx context.Context, profile *v1beta1.AppArmorProfile, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfile, error) {
+	result := v1beta1.AppArmorProfile{}
+	err := c.restClient.
+		Put().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		Name(profile.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(profile).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Delete takes name of the profile and deletes it
+func (c *appArmorProfileClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		Name(name).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of profiles
+func (c *appArmorProfileClient) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfiles).
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched profile
+func (c *appArmorProfileClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfile, error) {
+	result := v1beta1.AppArmorProfile{}
+	err := c.restClient.
+		Patch(pt).
+		Context(ctx).
+		Resource(apparmorProfiles).
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
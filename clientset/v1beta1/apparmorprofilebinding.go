@@ -0,0 +1,163 @@
+package v1beta1
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	apparmorProfileBindings = "apparmorprofilebindings"
+)
+
+// AppArmorProfileBindingInterface has methods to work with AppArmorProfileBinding resources
+type AppArmorProfileBindingInterface interface {
+	Create(ctx context.Context, binding *v1beta1.AppArmorProfileBinding, opts metav1.CreateOptions) (*v1beta1.AppArmorProfileBinding, error)
+	Update(ctx context.Context, binding *v1beta1.AppArmorProfileBinding, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfileBinding, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfileBinding, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileBindingList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfileBinding, error)
+}
+
+type appArmorProfileBindingClient struct {
+	restClient rest.Interface
+}
+
+// Get takes name of the binding, and returns the corresponding binding object, and an error if there is any
+func (c *appArmorProfileBindingClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfileBinding, error) {
+	result := v1beta1.AppArmorProfileBinding{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		Name(name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// List takes label and field selectors, and returns the list of bindings that match those selectors
+func (c *appArmorProfileBindingClient) List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileBindingList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	result := v1beta1.AppArmorProfileBindingList{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Watch returns a watch.Interface that watches the requested bindings
+func (c *appArmorProfileBindingClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	opts.Watch = true
+	return c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a binding and creates it, it returns the server's representation of the binding
+func (c *appArmorProfileBindingClient) Create(ctx context.Context, binding *v1beta1.AppArmorProfileBinding, opts metav1.CreateOptions) (*v1beta1.AppArmorProfileBinding, error) {
+	result := v1beta1.AppArmorProfileBinding{}
+	err := c.restClient.
+		Post().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(binding).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Update takes the representation of a binding and updates it, it returns the server's representation of the binding
+func (c *appArmorProfileBindingClient) Update(ctx context.Context, binding *v1beta1.AppArmorProfileBinding, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfileBinding, error) {
+	result := v1beta1.AppArmorProfileBinding{}
+	err := c.restClient.
+		Put().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		Name(binding.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(binding).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Delete takes name of the binding and deletes it
+func (c *appArmorProfileBindingClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		Name(name).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of bindings
+func (c *appArmorProfileBindingClient) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched binding
+func (c *appArmorProfileBindingClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfileBinding, error) {
+	result := v1beta1.AppArmorProfileBinding{}
+	err := c.restClient.
+		Patch(pt).
+		Context(ctx).
+		Resource(apparmorProfileBindings).
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
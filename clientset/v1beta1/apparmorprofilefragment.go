@@ -0,0 +1,163 @@
+package v1beta1
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	apparmorProfileFragments = "apparmorprofilefragments"
+)
+
+// AppArmorProfileFragmentInterface has methods to work with AppArmorProfileFragment resources
+type AppArmorProfileFragmentInterface interface {
+	Create(ctx context.Context, fragment *v1beta1.AppArmorProfileFragment, opts metav1.CreateOptions) (*v1beta1.AppArmorProfileFragment, error)
+	Update(ctx context.Context, fragment *v1beta1.AppArmorProfileFragment, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfileFragment, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfileFragment, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileFragmentList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfileFragment, error)
+}
+
+type appArmorProfileFragmentClient struct {
+	restClient rest.Interface
+}
+
+// Get takes name of the fragment, and returns the corresponding fragment object, and an error if there is any
+func (c *appArmorProfileFragmentClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1beta1.AppArmorProfileFragment, error) {
+	result := v1beta1.AppArmorProfileFragment{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		Name(name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// List takes label and field selectors, and returns the list of fragments that match those selectors
+func (c *appArmorProfileFragmentClient) List(ctx context.Context, opts metav1.ListOptions) (*v1beta1.AppArmorProfileFragmentList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	result := v1beta1.AppArmorProfileFragmentList{}
+	err := c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Watch returns a watch.Interface that watches the requested fragments
+func (c *appArmorProfileFragmentClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	opts.Watch = true
+	return c.restClient.
+		Get().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a fragment and creates it, it returns the server's representation of the fragment
+func (c *appArmorProfileFragmentClient) Create(ctx context.Context, fragment *v1beta1.AppArmorProfileFragment, opts metav1.CreateOptions) (*v1beta1.AppArmorProfileFragment, error) {
+	result := v1beta1.AppArmorProfileFragment{}
+	err := c.restClient.
+		Post().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(fragment).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Update takes the representation of a fragment and updates it, it returns the server's representation of the fragment
+func (c *appArmorProfileFragmentClient) Update(ctx context.Context, fragment *v1beta1.AppArmorProfileFragment, opts metav1.UpdateOptions) (*v1beta1.AppArmorProfileFragment, error) {
+	result := v1beta1.AppArmorProfileFragment{}
+	err := c.restClient.
+		Put().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		Name(fragment.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(fragment).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
+
+// Delete takes name of the fragment and deletes it
+func (c *appArmorProfileFragmentClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		Name(name).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of fragments
+func (c *appArmorProfileFragmentClient) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+
+	return c.restClient.
+		Delete().
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched fragment
+func (c *appArmorProfileFragmentClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1beta1.AppArmorProfileFragment, error) {
+	result := v1beta1.AppArmorProfileFragment{}
+	err := c.restClient.
+		Patch(pt).
+		Context(ctx).
+		Resource(apparmorProfileFragments).
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do().
+		Into(&result)
+
+	return &result, err
+}
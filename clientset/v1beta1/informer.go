@@ -0,0 +1,131 @@
+package v1beta1
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// AppArmorProfileInformer provides access to a shared informer and lister for AppArmorProfile objects
+type AppArmorProfileInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() AppArmorProfileLister
+}
+
+// NewAppArmorProfileInformer returns a new informer for AppArmorProfile objects. Always prefer using the shared
+// informer factory, which reduces memory footprint and number of connections to the server.
+func NewAppArmorProfileInformer(client AppArmorV1Beta1Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.ApparmorProfiles().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.ApparmorProfiles().Watch(context.TODO(), options)
+			},
+		},
+		&v1beta1.AppArmorProfile{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+// SharedInformerFactory provides shared informers for the resources of the crd.security.sysdig.com/v1beta1 group version
+type SharedInformerFactory interface {
+	// Start starts the informers requested so far, it can be called again when more informers are requested
+	Start(stopCh <-chan struct{})
+	// WaitForCacheSync waits for the caches of all the started informers to be synced
+	WaitForCacheSync(stopCh <-chan struct{}) map[string]bool
+	AppArmorProfiles() AppArmorProfileInformer
+}
+
+type sharedInformerFactory struct {
+	client       AppArmorV1Beta1Interface
+	resyncPeriod time.Duration
+
+	lock             sync.Mutex
+	informers        map[string]cache.SharedIndexInformer
+	startedInformers map[string]bool
+}
+
+// NewSharedInformerFactory returns a new shared informer factory
+func NewSharedInformerFactory(client AppArmorV1Beta1Interface, resyncPeriod time.Duration) SharedInformerFactory {
+	return &sharedInformerFactory{
+		client:           client,
+		resyncPeriod:     resyncPeriod,
+		informers:        map[string]cache.SharedIndexInformer{},
+		startedInformers: map[string]bool{},
+	}
+}
+
+func (f *sharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for name, informer := range f.informers {
+		if !f.startedInformers[name] {
+			go informer.Run(stopCh)
+			f.startedInformers[name] = true
+		}
+	}
+}
+
+func (f *sharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[string]bool {
+	informers := func() map[string]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[string]cache.SharedIndexInformer{}
+		for name, informer := range f.informers {
+			if f.startedInformers[name] {
+				informers[name] = informer
+			}
+		}
+		return informers
+	}()
+
+	res := map[string]bool{}
+	for name, informer := range informers {
+		res[name] = cache.WaitForCacheSync(stopCh, informer.HasSynced)
+	}
+
+	return res
+}
+
+func (f *sharedInformerFactory) informerFor(name string, newFunc func() cache.SharedIndexInformer) cache.SharedIndexInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if informer, ok := f.informers[name]; ok {
+		return informer
+	}
+
+	informer := newFunc()
+	f.informers[name] = informer
+
+	return informer
+}
+
+func (f *sharedInformerFactory) AppArmorProfiles() AppArmorProfileInformer {
+	return &appArmorProfileInformer{factory: f}
+}
+
+type appArmorProfileInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (i *appArmorProfileInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apparmorProfiles, func() cache.SharedIndexInformer {
+		return NewAppArmorProfileInformer(i.factory.client, i.factory.resyncPeriod, cache.Indexers{})
+	})
+}
+
+func (i *appArmorProfileInformer) Lister() AppArmorProfileLister {
+	return NewAppArmorProfileLister(i.Informer().GetIndexer())
+}
@@ -0,0 +1,142 @@
+// Package crd provides the CRDs installed by the manager. The manifests are generated from the API types
+// with controller-gen (make manifests) and embedded into zz_generated.go (go generate ./crd).
+package crd
+
+//go:generate go run gen.go
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+)
+
+const (
+	// SpecHashAnnotation contains the hash of the spec an installed CRD was created or last updated from
+	SpecHashAnnotation = "crd.security.sysdig.com/spec-hash"
+
+	appArmorProfileManifest = "crd.security.sysdig.com_apparmorprofiles.yaml"
+
+	appArmorProfileFragmentManifest = "crd.security.sysdig.com_apparmorprofilefragments.yaml"
+
+	appArmorProfileBindingManifest = "crd.security.sysdig.com_apparmorprofilebindings.yaml"
+
+	// ConversionPath is where the webhook command serves the conversion webhook
+	ConversionPath = "/convert"
+)
+
+// WebhookConfig tells the API server how to reach the conversion webhook served by the webhook command
+type WebhookConfig struct {
+	// Namespace and Name of the service in front of the webhook
+	Namespace string
+	Name      string
+	// Port of the service, 443 if not set
+	Port int32
+	// CABundle is the PEM encoded CA the webhook serving certificate is signed by
+	CABundle []byte
+	// ValidatePods registers the webhook checking the AppArmor profiles of the pods
+	ValidatePods bool
+	// MutatePods registers the webhook assigning profiles to the pods selected by AppArmorProfileBinding objects
+	MutatePods bool
+}
+
+// AppArmorProfile returns the AppArmorProfile CRD. The versions are converted by the webhook, without
+// a webhook only v1alpha1 is served since the API server can't convert the versions on its own.
+func AppArmorProfile(webhook *WebhookConfig) (*apiextensions.CustomResourceDefinition, error) {
+	crd, err := load(appArmorProfileManifest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook == nil {
+		versions := []apiextensions.CustomResourceDefinitionVersion{}
+
+		for _, v := range crd.Spec.Versions {
+			if v.Name == v1alpha1.GroupVersion {
+				v.Storage = true
+				versions = append(versions, v)
+			}
+		}
+
+		crd.Spec.Versions = versions
+		crd.Spec.Conversion = &apiextensions.CustomResourceConversion{Strategy: apiextensions.NoneConverter}
+	} else {
+		port := webhook.Port
+
+		if port == 0 {
+			port = 443
+		}
+
+		path := ConversionPath
+
+		crd.Spec.Conversion = &apiextensions.CustomResourceConversion{
+			Strategy: apiextensions.WebhookConverter,
+			Webhook: &apiextensions.WebhookConversion{
+				ClientConfig: &apiextensions.WebhookClientConfig{
+					Service: &apiextensions.ServiceReference{
+						Namespace: webhook.Namespace,
+						Name:      webhook.Name,
+						Path:      &path,
+						Port:      &port,
+					},
+					CABundle: webhook.CABundle,
+				},
+				ConversionReviewVersions: []string{"v1"},
+			},
+		}
+	}
+
+	return crd, setSpecHash(crd)
+}
+
+// AppArmorProfileFragment returns the AppArmorProfileFragment CRD, it only has the v1beta1 version
+func AppArmorProfileFragment() (*apiextensions.CustomResourceDefinition, error) {
+	return load(appArmorProfileFragmentManifest)
+}
+
+// AppArmorProfileBinding returns the AppArmorProfileBinding CRD, it only has the v1beta1 version
+func AppArmorProfileBinding() (*apiextensions.CustomResourceDefinition, error) {
+	return load(appArmorProfileBindingManifest)
+}
+
+func load(file string) (*apiextensions.CustomResourceDefinition, error) {
+	manifest, ok := manifests[file]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown CRD manifest: %s", file)
+	}
+
+	crd := &apiextensions.CustomResourceDefinition{}
+
+	err := yaml.UnmarshalStrict([]byte(manifest), crd)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRD manifest %s: %v", file, err)
+	}
+
+	return crd, setSpecHash(crd)
+}
+
+// setSpecHash annotates the CRD with the hash of its spec
+func setSpecHash(crd *apiextensions.CustomResourceDefinition) error {
+	spec, err := json.Marshal(crd.Spec)
+
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(spec)
+
+	if crd.Annotations == nil {
+		crd.Annotations = map[string]string{}
+	}
+	crd.Annotations[SpecHashAnnotation] = hex.EncodeToString(sum[:8])
+
+	return nil
+}
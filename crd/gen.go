@@ -0,0 +1,56 @@
+//go:build ignore
+// +build ignore
+
+// gen embeds the CRD manifests of this directory into zz_generated.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	files, err := filepath.Glob("*.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("// Code generated by gen.go; DO NOT EDIT.\n\npackage crd\n\n")
+	buf.WriteString("// manifests contains the CRD manifests indexed by file name\n")
+	buf.WriteString("var manifests = map[string]string{\n")
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		content := string(data)
+		literal := "`" + content + "`"
+		if strings.Contains(content, "`") {
+			literal = strconv.Quote(content)
+		}
+
+		fmt.Fprintf(&buf, "%q: %s,\n", file, literal)
+	}
+
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ioutil.WriteFile("zz_generated.go", src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,159 @@
+package crd
+
+import (
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+)
+
+const (
+	// ValidationPath is where the webhook command serves the validating webhook
+	ValidationPath = "/validate"
+
+	// PodValidationPath is where the webhook command serves the webhook checking the profiles of the pods
+	PodValidationPath = "/validate-pods"
+
+	// MutationPath is where the webhook command serves the webhook assigning profiles to the pods
+	MutationPath = "/mutate-pods"
+
+	// ValidatingWebhookName is the name of the ValidatingWebhookConfiguration of the AppArmorProfile objects
+	ValidatingWebhookName = "kube-apparmor-manager"
+
+	// MutatingWebhookName is the name of the MutatingWebhookConfiguration of the pods
+	MutatingWebhookName = "kube-apparmor-manager"
+)
+
+// ValidatingWebhook returns the configuration registering the validating webhook for AppArmorProfile,
+// AppArmorProfileFragment and AppArmorProfileBinding objects, invalid objects are refused when they are created or updated
+// and fragments included by profiles when they are deleted.
+// The pod webhook is registered too if enabled, pods are admitted when it is not available.
+func ValidatingWebhook(webhook *WebhookConfig) *admissionregistration.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregistration.Fail
+	sideEffects := admissionregistration.SideEffectClassNone
+	scope := admissionregistration.ClusterScope
+
+	config := &admissionregistration.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ValidatingWebhookName,
+		},
+		Webhooks: []admissionregistration.ValidatingWebhook{
+			{
+				Name:         "validate.crd.security.sysdig.com",
+				ClientConfig: webhook.clientConfig(ValidationPath),
+				Rules: []admissionregistration.RuleWithOperations{
+					{
+						Operations: []admissionregistration.OperationType{
+							admissionregistration.Create,
+							admissionregistration.Update,
+						},
+						Rule: admissionregistration.Rule{
+							APIGroups:   []string{v1beta1.SchemeGroupVersion.Group},
+							APIVersions: []string{v1alpha1.GroupVersion, v1beta1.GroupVersion},
+							Resources:   []string{v1alpha1.Plural, v1beta1.FragmentPlural, v1beta1.BindingPlural},
+							Scope:       &scope,
+						},
+					},
+					{
+						Operations: []admissionregistration.OperationType{admissionregistration.Delete},
+						Rule: admissionregistration.Rule{
+							APIGroups:   []string{v1beta1.SchemeGroupVersion.Group},
+							APIVersions: []string{v1beta1.GroupVersion},
+							Resources:   []string{v1beta1.FragmentPlural},
+							Scope:       &scope,
+						},
+					},
+				},
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+		},
+	}
+
+	if !webhook.ValidatePods {
+		return config
+	}
+
+	ignore := admissionregistration.Ignore
+	namespaced := admissionregistration.NamespacedScope
+	timeout := int32(5)
+
+	config.Webhooks = append(config.Webhooks, admissionregistration.ValidatingWebhook{
+		Name:         "pods.validate.crd.security.sysdig.com",
+		ClientConfig: webhook.clientConfig(PodValidationPath),
+		Rules: []admissionregistration.RuleWithOperations{
+			{
+				Operations: []admissionregistration.OperationType{admissionregistration.Create},
+				Rule: admissionregistration.Rule{
+					APIGroups:   []string{""},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"pods"},
+					Scope:       &namespaced,
+				},
+			},
+		},
+		FailurePolicy:           &ignore,
+		SideEffects:             &sideEffects,
+		TimeoutSeconds:          &timeout,
+		AdmissionReviewVersions: []string{"v1"},
+	})
+
+	return config
+}
+
+// MutatingWebhook returns the configuration registering the webhook assigning the profiles of the
+// AppArmorProfileBinding objects to new pods, pods are admitted unchanged when it is not available
+func MutatingWebhook(webhook *WebhookConfig) *admissionregistration.MutatingWebhookConfiguration {
+	ignore := admissionregistration.Ignore
+	sideEffects := admissionregistration.SideEffectClassNone
+	namespaced := admissionregistration.NamespacedScope
+	timeout := int32(5)
+
+	return &admissionregistration.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MutatingWebhookName,
+		},
+		Webhooks: []admissionregistration.MutatingWebhook{
+			{
+				Name:         "pods.mutate.crd.security.sysdig.com",
+				ClientConfig: webhook.clientConfig(MutationPath),
+				Rules: []admissionregistration.RuleWithOperations{
+					{
+						Operations: []admissionregistration.OperationType{admissionregistration.Create},
+						Rule: admissionregistration.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"pods"},
+							Scope:       &namespaced,
+						},
+					},
+				},
+				FailurePolicy:           &ignore,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &timeout,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+		},
+	}
+}
+
+// clientConfig returns how the API server reaches the webhook served on path
+func (webhook *WebhookConfig) clientConfig(path string) admissionregistration.WebhookClientConfig {
+	port := webhook.Port
+
+	if port == 0 {
+		port = 443
+	}
+
+	return admissionregistration.WebhookClientConfig{
+		Service: &admissionregistration.ServiceReference{
+			Namespace: webhook.Namespace,
+			Name:      webhook.Name,
+			Path:      &path,
+			Port:      &port,
+		},
+		CABundle: webhook.CABundle,
+	}
+}
@@ -0,0 +1,158 @@
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+)
+
+// Inventory modes
+const (
+	// ModeReplace uses the inventory hosts only
+	ModeReplace = "replace"
+	// ModeAugment uses the inventory hosts in addition to the Kubernetes nodes
+	ModeAugment = "augment"
+)
+
+// Inventory is a static list of hosts which are not (or not reachable as) Kubernetes nodes
+type Inventory struct {
+	// SSH contains the SSH settings shared by all hosts
+	SSH   *types.SSHConfig `json:"ssh,omitempty"`
+	Hosts []Host           `json:"hosts"`
+}
+
+// Host is a host of the inventory
+type Host struct {
+	Name      string            `json:"name"`
+	Addresses []Address         `json:"addresses"`
+	Role      string            `json:"role,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	SSH       *types.SSHConfig  `json:"ssh,omitempty"`
+}
+
+// Address is a host address, the type is one of the node address types (InternalIP, ExternalIP, InternalDNS, ExternalDNS, Hostname)
+type Address struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// Load reads an inventory file
+func Load(path string) (*Inventory, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{}
+
+	err = yaml.UnmarshalStrict(data, inv)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %v", path, err)
+	}
+
+	err = inv.validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid inventory %s: %v", path, err)
+	}
+
+	return inv, nil
+}
+
+func (inv *Inventory) validate() error {
+	names := map[string]bool{}
+
+	for _, h := range inv.Hosts {
+		if h.Name == "" {
+			return fmt.Errorf("host without name")
+		}
+
+		if names[h.Name] {
+			return fmt.Errorf("duplicate host: %s", h.Name)
+		}
+		names[h.Name] = true
+
+		for _, addr := range h.Addresses {
+			err := types.ValidateAddressType(addr.Type)
+			if err != nil {
+				return fmt.Errorf("host %s: %v", h.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetNodes returns the inventory hosts as nodes, hosts which don't match the filter are marked as skipped
+func (inv *Inventory) GetNodes(filter types.NodeFilter) (types.NodeList, error) {
+	selector, err := labels.Parse(filter.Selector)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList := types.NodeList{}
+
+	for _, h := range inv.Hosts {
+		// hosts not matching the label selector are left out, the same as the Kubernetes nodes
+		if !selector.Matches(labels.Set(h.Labels)) {
+			continue
+		}
+
+		n := types.NewNode()
+		n.NodeName = h.Name
+		n.Role = h.Role
+		n.Source = types.SourceInventory
+
+		if n.Role == "" {
+			n.Role = types.Worker
+		}
+
+		for _, addr := range h.Addresses {
+			n.AddAddress(addr.Type, addr.Address)
+		}
+
+		n.SSH = mergeSSHConfig(inv.SSH, h.SSH)
+
+		if !filter.Match(h.Name) {
+			n.Skip(types.SkipExcluded)
+		}
+
+		nodeList = append(nodeList, n)
+	}
+
+	return nodeList, nil
+}
+
+// mergeSSHConfig returns the host SSH settings with the inventory wide settings as fallback
+func mergeSSHConfig(defaults, host *types.SSHConfig) *types.SSHConfig {
+	if defaults == nil {
+		return host
+	}
+
+	if host == nil {
+		return defaults
+	}
+
+	merged := *defaults
+
+	if host.User != "" {
+		merged.User = host.User
+	}
+
+	if host.Port != 0 {
+		merged.Port = host.Port
+	}
+
+	if host.KeyFile != "" {
+		merged.KeyFile = host.KeyFile
+	}
+
+	return &merged
+}
@@ -0,0 +1,304 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
+)
+
+// Check describes what a check finds
+type Check struct {
+	Name        string
+	Severity    Severity
+	Description string
+}
+
+// Checks are the checks indexed by ID
+var Checks = map[string]Check{
+	"AA000": {"invalid-object", SeverityError, "The object is refused by the validation, the other checks are skipped"},
+	"AA001": {"write-and-exec", SeverityError, "A path can be both written and executed, a process can run the code it writes"},
+	"AA002": {"write-anywhere", SeverityError, "A /** wildcard or a bare file rule allows writing every file"},
+	"AA003": {"shell-inherit-exec", SeverityWarning, "A shell is executed with ix, it runs any command under the same profile"},
+	"AA004": {"dangerous-capability", SeverityError, "sys_admin or sys_module is allowed, or all the capabilities are"},
+	"AA005": {"unrestricted-mount", SeverityError, "A mount, remount or pivot_root rule doesn't restrict the file systems, sources or mount points"},
+	"AA006": {"unrestricted-ptrace", SeverityWarning, "A ptrace rule doesn't restrict the peers, the processes of other profiles can be traced"},
+	"AA007": {"raw-network", SeverityWarning, "Raw sockets are allowed, the container can forge packets"},
+	"AA008": {"missing-sysrq-deny", SeverityWarning, "The profile doesn't deny writing /proc/sysrq-trigger, as the docker-default profile does"},
+	"AA009": {"duplicate-rule", SeverityInfo, "A rule is written twice in the same profile"},
+	"AA010": {"unused-rule", SeverityInfo, "A rule allows nothing more than another rule, or everything it allows is denied"},
+}
+
+// CheckIDs returns the IDs of the checks in order
+func CheckIDs() []string {
+	ids := []string{}
+
+	for id := range Checks {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+const sysrqTrigger = "/proc/sysrq-trigger"
+
+var (
+	dangerousCapabilities = map[string]bool{"sys_admin": true, "sys_module": true}
+
+	shells = map[string]bool{
+		"sh": true, "bash": true, "dash": true, "ash": true, "zsh": true, "ksh": true, "mksh": true,
+		"csh": true, "tcsh": true, "fish": true, "busybox": true,
+	}
+
+	shellDirs = []string{"/bin/", "/usr/bin/", "/usr/local/bin/", "/sbin/", "/usr/sbin/"}
+
+	// unrestrictedMediation are the rules which allow everything without conditionals or arguments, by check
+	unrestrictedMediation = map[string]string{
+		"mount":      "AA005",
+		"remount":    "AA005",
+		"pivot_root": "AA005",
+		"ptrace":     "AA006",
+	}
+)
+
+// checkScope reports the risky rules of a scope, the rules included from fragments only count for the rules
+// the profile misses
+func (l *linter) checkScope(s scope, included []ruleRef) {
+	for i := range s.rules {
+		ref := &s.rules[i]
+
+		if ref.rule.Qualifier().Deny {
+			continue
+		}
+
+		switch r := ref.rule.(type) {
+		case *policy.FileRule:
+			l.checkFileRule(ref, r)
+		case *policy.CapabilityRule:
+			l.checkCapabilityRule(ref, r)
+		case *policy.NetworkRule:
+			l.checkNetworkRule(ref, r)
+		case *policy.MediationRule:
+			if id, ok := unrestrictedMediation[r.Keyword]; ok && len(r.Conds) == 0 && len(r.Args) == 0 && r.Target == "" {
+				l.report(id, ref, "%s is allowed without restriction", r.Keyword)
+			}
+		}
+	}
+
+	l.checkWriteExec(s)
+	l.checkDuplicates(s)
+
+	if s.profile && !deniesWrite(append(append([]ruleRef{}, s.rules...), included...), sysrqTrigger) {
+		l.report("AA008", nil, "the profile doesn't deny writing %s, add: deny %s rwklx,", sysrqTrigger, sysrqTrigger)
+	}
+}
+
+func (l *linter) checkFileRule(ref *ruleRef, r *policy.FileRule) {
+	if writes(r.Perms) && (r.Path == "" || strings.HasPrefix(r.Path, "/**")) {
+		l.report("AA002", ref, "every file can be written")
+	}
+
+	if !strings.Contains(r.Perms, "i") || !strings.Contains(r.Perms, "x") {
+		return
+	}
+
+	if shells := matchingShells(r.Path); len(shells) > 0 {
+		shell := shells[0]
+
+		if shell != r.Path {
+			shell = fmt.Sprintf("%s, e.g. %s,", r.Path, shell)
+		}
+
+		l.report("AA003", ref, "shell %s is executed with ix, use Px or Cx to a profile restricting it", shell)
+	}
+}
+
+func (l *linter) checkCapabilityRule(ref *ruleRef, r *policy.CapabilityRule) {
+	if len(r.Capabilities) == 0 {
+		l.report("AA004", ref, "all the capabilities are allowed")
+		return
+	}
+
+	for _, c := range r.Capabilities {
+		if dangerousCapabilities[c] {
+			l.report("AA004", ref, "capability %s is allowed", c)
+		}
+	}
+}
+
+func (l *linter) checkNetworkRule(ref *ruleRef, r *policy.NetworkRule) {
+	if len(r.Args) == 0 {
+		l.report("AA007", ref, "all the network access is allowed, including raw sockets")
+		return
+	}
+
+	for _, arg := range r.Args {
+		if arg == "raw" || arg == "packet" {
+			l.report("AA007", ref, "%s sockets are allowed", arg)
+			return
+		}
+	}
+}
+
+// checkWriteExec reports the paths which are written and executed, by the same rule or by two rules
+func (l *linter) checkWriteExec(s scope) {
+	files := fileRules(s.rules, false)
+
+	for i, a := range files {
+		r := a.rule.(*policy.FileRule)
+
+		if writes(r.Perms) && executes(r.Perms) {
+			l.report("AA001", &files[i], "%s can be written and executed", r.Path)
+			continue
+		}
+
+		if !executes(r.Perms) {
+			continue
+		}
+
+		for _, b := range files {
+			w := b.rule.(*policy.FileRule)
+
+			if writes(w.Perms) && w.Path == r.Path && w.Owner == r.Owner {
+				l.report("AA001", &files[i], "%s can be executed and is written by rule %s,", r.Path, w.String())
+				break
+			}
+		}
+	}
+}
+
+// checkDuplicates reports the rules written twice, and the allow file rules which are useless because of another rule
+func (l *linter) checkDuplicates(s scope) {
+	seen := map[string]bool{}
+
+	for i := range s.rules {
+		text := s.rules[i].rule.String()
+
+		if seen[text] {
+			l.report("AA009", &s.rules[i], "the rule is written before")
+			continue
+		}
+
+		seen[text] = true
+	}
+
+	allowed := fileRules(s.rules, false)
+	denied := fileRules(s.rules, true)
+
+	for i, a := range allowed {
+		r := a.rule.(*policy.FileRule)
+
+		if r.Target != "" || r.Qualifiers.Audit {
+			continue
+		}
+
+		if deny := denyingRule(denied, r); deny != nil {
+			l.report("AA010", &allowed[i], "everything it allows is denied by rule %s,", deny.String())
+			continue
+		}
+
+		for j, b := range allowed {
+			other := b.rule.(*policy.FileRule)
+
+			if i == j || other.String() == r.String() || other.Path != r.Path || other.Owner != r.Owner || other.Target != "" {
+				continue
+			}
+
+			// of two rules allowing the same, the second one is reported
+			if containsPerms(other.Perms, r.Perms) && (len(other.Perms) > len(r.Perms) || j < i) {
+				l.report("AA010", &allowed[i], "rule %s, allows it already", other.String())
+				break
+			}
+		}
+	}
+}
+
+// denyingRule returns the deny rule denying everything an allow rule allows, nil if there is none
+func denyingRule(denied []ruleRef, r *policy.FileRule) *policy.FileRule {
+	for _, d := range denied {
+		deny := d.rule.(*policy.FileRule)
+
+		if deny.Path == r.Path && (!deny.Owner || r.Owner) && containsPerms(deny.Perms, r.Perms) {
+			return deny
+		}
+	}
+
+	return nil
+}
+
+// fileRules returns the allow or the deny file rules
+func fileRules(rules []ruleRef, deny bool) []ruleRef {
+	files := []ruleRef{}
+
+	for _, ref := range rules {
+		if r, ok := ref.rule.(*policy.FileRule); ok && r.Deny == deny {
+			files = append(files, ref)
+		}
+	}
+
+	return files
+}
+
+// deniesWrite checks whether a deny rule covers writing a path
+func deniesWrite(rules []ruleRef, file string) bool {
+	for _, ref := range rules {
+		r, ok := ref.rule.(*policy.FileRule)
+
+		if ok && r.Deny && strings.Contains(r.Perms, "w") && (r.Path == "" || globMatches(r.Path, file)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchingShells returns the shell executables a path glob matches
+func matchingShells(glob string) []string {
+	matched := []string{}
+
+	if shells[path.Base(glob)] {
+		return []string{glob}
+	}
+
+	names := []string{}
+
+	for name := range shells {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, dir := range shellDirs {
+		for _, name := range names {
+			if globMatches(glob, dir+name) {
+				matched = append(matched, dir+name)
+			}
+		}
+	}
+
+	return matched
+}
+
+func writes(perms string) bool {
+	return strings.ContainsAny(perms, "wa")
+}
+
+// executes checks whether the permissions allow executing, m only maps the file into memory
+func executes(perms string) bool {
+	return strings.Contains(perms, "x")
+}
+
+// containsPerms checks whether some permissions include other ones, letter by letter
+func containsPerms(perms, other string) bool {
+	for _, c := range other {
+		if !strings.ContainsRune(perms, c) {
+			return false
+		}
+	}
+
+	return true
+}
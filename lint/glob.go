@@ -0,0 +1,108 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// variables are the values of the tunables variables the checks know, the other variables match nothing
+var variables = map[string]string{
+	"@{PROC}":   "/proc/",
+	"@{sys}":    "/sys/",
+	"@{run}":    "/run/",
+	"@{HOME}":   "{/home/*/,/root/}",
+	"@{pid}":    "{[1-9],[1-9][0-9]*}",
+	"@{pids}":   "{[1-9],[1-9][0-9]*}",
+	"@{tid}":    "{[1-9],[1-9][0-9]*}",
+	"@{etc_ro}": "/{usr/,}etc/",
+}
+
+var variableRegexp = regexp.MustCompile(`@\{[^}]*\}`)
+
+// globRegexp returns the regular expression matching the paths of an AppArmor path glob, nil if the glob
+// uses an unknown variable
+func globRegexp(glob string) *regexp.Regexp {
+	unknown := false
+
+	glob = variableRegexp.ReplaceAllStringFunc(glob, func(v string) string {
+		value, ok := variables[v]
+		unknown = unknown || !ok
+		return value
+	})
+
+	if unknown {
+		return nil
+	}
+
+	glob = collapseSlashes(glob)
+
+	var b strings.Builder
+	depth := 0
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+end]
+
+			if strings.HasPrefix(class, "^") {
+				class = "^/" + class[1:]
+			}
+
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end
+		case c == '{':
+			b.WriteString("(?:")
+			depth++
+		case c == '}' && depth > 0:
+			b.WriteString(")")
+			depth--
+		case c == ',' && depth > 0:
+			b.WriteString("|")
+		case c == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re, err := regexp.Compile("^" + b.String() + "$")
+
+	if err != nil {
+		return nil
+	}
+
+	return re
+}
+
+// collapseSlashes removes the repeated slashes of an expanded variable followed by a slash, e.g. @{PROC}/
+func collapseSlashes(s string) string {
+	for strings.Contains(s, "//") {
+		s = strings.Replace(s, "//", "/", -1)
+	}
+
+	return s
+}
+
+// globMatches checks whether an AppArmor path glob matches a path
+func globMatches(glob, path string) bool {
+	re := globRegexp(glob)
+
+	return re != nil && re.MatchString(path)
+}
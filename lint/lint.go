@@ -0,0 +1,310 @@
+// Package lint checks the rules of AppArmorProfile and AppArmorProfileFragment objects against security best
+// practices. The findings have a stable ID and a severity, and can be suppressed per object with an annotation.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
+)
+
+// IgnoreAnnotation lists the IDs of the checks ignored for an object, separated by commas
+const IgnoreAnnotation = "crd.security.sysdig.com/lint-ignore"
+
+// Severity is how risky a finding is
+type Severity string
+
+// Finding severities, from the most to the least severe
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityError:   3,
+	SeverityWarning: 2,
+	SeverityInfo:    1,
+}
+
+// ParseSeverity returns the severity of a name, an error if it is unknown
+func ParseSeverity(name string) (Severity, error) {
+	s := Severity(name)
+
+	if _, ok := severityRanks[s]; !ok {
+		return "", fmt.Errorf("unknown severity %q, expected error, warning or info", name)
+	}
+
+	return s, nil
+}
+
+// AtLeast checks whether a severity is as severe as another one
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Finding is a risky pattern found in the rules of an object
+type Finding struct {
+	ID       string   `json:"id"`
+	Severity Severity `json:"severity"`
+	Kind     string   `json:"kind"`
+	Object   string   `json:"object"`
+	// Source is the file the object is read from, empty if it comes from the cluster
+	Source string `json:"source,omitempty"`
+	// Field is where the rule is written: rules, profile or structured rules
+	Field string `json:"field,omitempty"`
+	// Line of the rule in the field, 0 for the structured rules and the findings about a whole profile
+	Line    int    `json:"line,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+	// Suppressed tells that the check is ignored for the object by its annotation
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+// Location returns where the rule of a finding is written, e.g. rules line 3
+func (f Finding) Location() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s line %d", f.Field, f.Line)
+	}
+
+	return f.Field
+}
+
+// Findings are the findings of the linted objects
+type Findings []Finding
+
+// Failed checks whether a finding which isn't suppressed is at least as severe as a severity
+func (f Findings) Failed(threshold Severity) bool {
+	for _, finding := range f {
+		if !finding.Suppressed && finding.Severity.AtLeast(threshold) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reported returns the findings which aren't suppressed
+func (f Findings) Reported() Findings {
+	reported := Findings{}
+
+	for _, finding := range f {
+		if !finding.Suppressed {
+			reported = append(reported, finding)
+		}
+	}
+
+	return reported
+}
+
+// ruleRef is a rule along with where it is written
+type ruleRef struct {
+	rule  policy.Rule
+	field string
+}
+
+// line returns the line of the rule in its field, 0 for a generated rule
+func (r ruleRef) line() int {
+	return r.rule.Pos().Line
+}
+
+// scope are the rules of a profile body, nested profiles are scopes of their own
+type scope struct {
+	rules []ruleRef
+	// profile tells that the scope is a complete profile, which is checked for the rules it misses
+	profile bool
+}
+
+// Lint checks the profiles and fragments of objects, the findings are sorted by object
+func Lint(objects *client.ProfileObjects) Findings {
+	findings := Findings{}
+	fragments := map[string]*v1beta1.AppArmorProfileFragment{}
+
+	for i := range objects.Fragments {
+		fragments[objects.Fragments[i].Name] = &objects.Fragments[i]
+	}
+
+	for i := range objects.Profiles {
+		p := &objects.Profiles[i]
+		l := newLinter(v1alpha1.Kind, p.Name, objects.Source(v1alpha1.Kind, p.Name), p.Annotations)
+		l.profile(p, fragments, objects.IsV1alpha1(p.Name))
+		findings = append(findings, l.findings...)
+	}
+
+	for i := range objects.Fragments {
+		f := &objects.Fragments[i]
+		l := newLinter(v1beta1.FragmentKind, f.Name, objects.Source(v1beta1.FragmentKind, f.Name), f.Annotations)
+		l.fragment(f)
+		findings = append(findings, l.findings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+
+		return a.Line < b.Line
+	})
+
+	return findings
+}
+
+// linter collects the findings of an object
+type linter struct {
+	kind     string
+	name     string
+	source   string
+	ignored  map[string]bool
+	findings Findings
+}
+
+func newLinter(kind, name, source string, annotations map[string]string) *linter {
+	ignored := map[string]bool{}
+
+	for _, id := range strings.Split(annotations[IgnoreAnnotation], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ignored[strings.ToUpper(id)] = true
+		}
+	}
+
+	return &linter{kind: kind, name: name, source: source, ignored: ignored}
+}
+
+// report adds a finding about a rule, or about the whole object if the rule is nil
+func (l *linter) report(id string, ref *ruleRef, format string, args ...interface{}) {
+	check := Checks[id]
+
+	finding := Finding{
+		ID:         id,
+		Severity:   check.Severity,
+		Kind:       l.kind,
+		Object:     l.name,
+		Source:     l.source,
+		Message:    fmt.Sprintf(format, args...),
+		Suppressed: l.ignored[id],
+	}
+
+	if ref != nil {
+		finding.Field = ref.field
+		finding.Line = ref.line()
+		finding.Rule = ref.rule.String() + ","
+	}
+
+	l.findings = append(l.findings, finding)
+}
+
+// profile checks the rules of a profile, the deny rules of the fragments it includes count for the rules it misses,
+// a profile converted from v1alpha1 may have no rules
+func (l *linter) profile(p *v1beta1.AppArmorProfile, fragments map[string]*v1beta1.AppArmorProfileFragment, legacy bool) {
+	validate := v1beta1.ValidateAppArmorProfile
+
+	if legacy {
+		validate = v1beta1.ValidateV1alpha1AppArmorProfile
+	}
+
+	err := validate(p)
+
+	if err != nil {
+		l.report("AA000", nil, "invalid profile: %v", err)
+		return
+	}
+
+	included := []ruleRef{}
+
+	for _, name := range p.Spec.Fragments {
+		f, ok := fragments[string(name)]
+
+		if !ok || v1beta1.ValidateAppArmorProfileFragment(f) != nil {
+			continue
+		}
+
+		fragmentScope := rulesScope(f.Spec.RuleSet, f.Spec.Rules)
+		included = append(included, fragmentScope[0].rules...)
+	}
+
+	var scopes []scope
+
+	if p.Spec.Profile != "" {
+		scopes = profileScopes(p.Spec.Profile)
+	} else {
+		scopes = rulesScope(p.Spec.RuleSet, p.Spec.Rules)
+		scopes[0].profile = true
+	}
+
+	for _, s := range scopes {
+		l.checkScope(s, included)
+	}
+}
+
+// fragment checks the rules of a fragment, it isn't a complete profile
+func (l *linter) fragment(f *v1beta1.AppArmorProfileFragment) {
+	err := v1beta1.ValidateAppArmorProfileFragment(f)
+
+	if err != nil {
+		l.report("AA000", nil, "invalid fragment: %v", err)
+		return
+	}
+
+	for _, s := range rulesScope(f.Spec.RuleSet, f.Spec.Rules) {
+		l.checkScope(s, nil)
+	}
+}
+
+// rulesScope returns the scopes of the structured rules and the rules of an object, the first one is the body
+func rulesScope(set v1beta1.RuleSet, rules string) []scope {
+	body := scope{}
+
+	for _, r := range set.PolicyRules() {
+		body.rules = append(body.rules, ruleRef{rule: r, field: "structured rules"})
+	}
+
+	// the rules are validated already
+	nodes, _ := policy.ParseRules(rules)
+	nested := []scope{}
+
+	body.rules = append(body.rules, collect(nodes, "rules", &nested)...)
+
+	return append([]scope{body}, nested...)
+}
+
+// profileScopes returns the scopes of the profiles of a complete profile text
+func profileScopes(text string) []scope {
+	// the profile is validated already
+	pol, _ := policy.Parse(text)
+	scopes := []scope{}
+
+	for _, p := range pol.Profiles() {
+		nested := []scope{}
+		scopes = append(scopes, scope{rules: collect(p.Body, "profile", &nested), profile: true})
+		scopes = append(scopes, nested...)
+	}
+
+	return scopes
+}
+
+// collect returns the rules of a body, the nested profiles are added to scopes
+func collect(nodes []policy.Node, field string, scopes *[]scope) []ruleRef {
+	rules := []ruleRef{}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case policy.Rule:
+			rules = append(rules, ruleRef{rule: n, field: field})
+		case *policy.Profile:
+			*scopes = append(*scopes, scope{rules: collect(n.Body, field, scopes)})
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,212 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Output formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "kube-apparmor-manager"
+	toolURI      = "https://github.com/sysdiglabs/kube-apparmor-manager"
+)
+
+// sarifLevels are the SARIF levels of the severities
+var sarifLevels = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "note",
+}
+
+// Write writes the findings in a format, the text format leaves out the suppressed findings
+func (f Findings) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		f.writeText(w)
+		return nil
+	case FormatJSON:
+		return writeJSON(w, f)
+	case FormatSARIF:
+		return writeJSON(w, f.sarif())
+	}
+
+	return fmt.Errorf("unknown output format: %s", format)
+}
+
+func (f Findings) writeText(w io.Writer) {
+	reported := f.Reported()
+
+	if len(reported) == 0 {
+		fmt.Fprintf(w, "No findings, %d suppressed\n", len(f))
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Object", "Location", "ID", "Severity", "Rule", "Details"})
+
+	data := [][]string{}
+
+	for _, finding := range reported {
+		object := finding.Kind + "/" + finding.Object
+
+		if finding.Source != "" {
+			object += " (" + finding.Source + ")"
+		}
+
+		data = append(data, []string{object, finding.Location(), finding.ID, string(finding.Severity), finding.Rule, finding.Message})
+	}
+
+	table.AppendBulk(data)
+	table.Render()
+
+	if suppressed := len(f) - len(reported); suppressed > 0 {
+		fmt.Fprintf(w, "%d findings suppressed by the %s annotation\n", suppressed, IgnoreAnnotation)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+// sarifLocation is the file of the object if it is read from one, the lines of the findings are relative to the
+// field of the object and not to the file, they are part of the logical location
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification"`
+}
+
+// sarif returns the SARIF 2.1.0 log of the findings, the suppressed findings are included as such
+func (f Findings) sarif() sarifLog {
+	driver := sarifDriver{Name: toolName, InformationURI: toolURI, Rules: []sarifRule{}}
+
+	for _, id := range CheckIDs() {
+		check := Checks[id]
+
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   id,
+			Name:                 check.Name,
+			ShortDescription:     sarifMessage{Text: check.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[check.Severity]},
+		})
+	}
+
+	results := []sarifResult{}
+
+	for _, finding := range f {
+		name := finding.Kind + "/" + finding.Object
+
+		if location := finding.Location(); location != "" {
+			name += " " + location
+		}
+
+		location := sarifLocation{
+			LogicalLocations: []sarifLogicalLocation{{Name: finding.Object, FullyQualifiedName: name, Kind: "object"}},
+		}
+
+		if finding.Source != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: finding.Source}}
+		}
+
+		message := finding.Message
+
+		if finding.Rule != "" {
+			message += ": " + finding.Rule
+		}
+
+		result := sarifResult{
+			RuleID:    finding.ID,
+			Level:     sarifLevels[finding.Severity],
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{location},
+		}
+
+		if finding.Suppressed {
+			result.Suppressions = []sarifSuppression{{Kind: "external", Justification: "ignored by the " + IgnoreAnnotation + " annotation"}}
+		}
+
+		results = append(results, result)
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/sysdiglabs/kube-apparmor-manager/aa"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/client"
+	"github.com/sysdiglabs/kube-apparmor-manager/crd"
+	"github.com/sysdiglabs/kube-apparmor-manager/inventory"
+	"github.com/sysdiglabs/kube-apparmor-manager/lint"
+	"github.com/sysdiglabs/kube-apparmor-manager/types"
+	"github.com/sysdiglabs/kube-apparmor-manager/webhook"
+	"github.com/sysdiglabs/kube-apparmor-manager/workload"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
@@ -21,6 +32,20 @@ func main() {
 
 	var logLevel string
 	var useInternalIP bool
+	var addressPreference []string
+	var nodeFilter types.NodeFilter
+	var inventoryFile, inventoryMode string
+	var profileFiles []string
+	var installOptions = aa.DefaultInstallOptions
+	var uninstallOptions aa.UninstallOptions
+	var syncOptions aa.SyncOptions
+	var assumeYes bool
+	var webhookService, webhookCABundle string
+	var webhookServicePort int32
+	var validatePods, mutatePods bool
+	var podCheck, profileInjection string
+	var webhookPort int
+	var tlsCertFile, tlsKeyFile string
 
 	log.SetFormatter(&log.TextFormatter{
 		FullTimestamp: true,
@@ -45,24 +70,58 @@ func main() {
 			}
 
 			log.SetLevel(lvl)
-			appArmor.UseInternalIP(useInternalIP)
+
+			if useInternalIP {
+				addressPreference = append([]string{types.AddressInternalIP}, addressPreference...)
+			}
+
+			err = types.ValidateAddressPreference(addressPreference)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			appArmor.SetAddressPreference(addressPreference)
+			appArmor.SetNodeFilter(nodeFilter)
+			appArmor.SetProfileFiles(profileFiles)
+
+			if inventoryFile != "" {
+				err = appArmor.SetInventory(inventoryFile, inventoryMode)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVar(&logLevel, "level", "info", "Log level")
+	rootCmd.PersistentFlags().StringSliceVarP(&addressPreference, "address-type", "a", types.DefaultAddressPreference, "Node address types to connect to, in order of preference (InternalIP, ExternalIP, InternalDNS, ExternalDNS, Hostname), an IP type may be restricted to a family, e.g. InternalIP/IPv6")
+	rootCmd.PersistentFlags().StringVar(&inventoryFile, "inventory", "", "Inventory file listing hosts outside of the Kubernetes cluster")
+	rootCmd.PersistentFlags().StringVar(&inventoryMode, "inventory-mode", inventory.ModeAugment, "Whether the inventory hosts replace or augment the Kubernetes nodes (replace, augment)")
 	rootCmd.PersistentFlags().BoolVarP(&useInternalIP, "internal-ip", "i", false, "Use internal ip to sync")
+	rootCmd.PersistentFlags().MarkDeprecated("internal-ip", "use --address-type InternalIP instead")
 
 	var initCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Install CRD in the cluster and AppArmor services on worker nodes",
 		Long:  "Install CRD in the Kubernetes cluster database and AppArmor services on worker nodes",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := appArmor.InstallCRD()
+			webhookConfig, err := newWebhookConfig(webhookService, webhookServicePort, webhookCABundle)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if webhookConfig != nil {
+				webhookConfig.ValidatePods = validatePods
+				webhookConfig.MutatePods = mutatePods
+			}
+
+			err = appArmor.InstallCRD(webhookConfig)
 			if err != nil {
 				log.Fatalf("failed to install CRD: %v", err)
 			}
 
-			err = appArmor.InstallAppArmor()
+			nodes, err := appArmor.InstallAppArmor(installOptions)
+			nodes.PrintSkipped()
 			if err != nil {
 				log.Fatalf("failed to install AppArmor service: %v", err)
 			}
@@ -74,7 +133,8 @@ func main() {
 		Short: "Synchronize the AppArmor profiles from the Kubernetes database (etcd) to worker nodes",
 		Long:  "Synchronize the AppArmor profiles from the Kubernetes database (etcd) to worker nodes",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := appArmor.Sync()
+			nodes, err := appArmor.Sync(syncOptions)
+			nodes.PrintSkipped()
 			if err != nil {
 				log.Fatalf("sync error: %v", err)
 			}
@@ -109,10 +169,246 @@ func main() {
 		},
 	}
 
+	initCmd.Flags().BoolVar(&installOptions.Drain, "drain", installOptions.Drain, "Cordon and drain Kubernetes nodes before restarting them")
+	initCmd.Flags().IntVar(&installOptions.MaxUnavailable, "max-unavailable", installOptions.MaxUnavailable, "Number of nodes restarted at the same time")
+	initCmd.Flags().DurationVar(&installOptions.DrainTimeout, "drain-timeout", installOptions.DrainTimeout, "Time to wait for the pods of a node to be evicted")
+	initCmd.Flags().DurationVar(&installOptions.RebootTimeout, "reboot-timeout", installOptions.RebootTimeout, "Time to wait for a node to be Ready with AppArmor enabled after the restart")
+
+	initCmd.Flags().StringVar(&webhookService, "webhook-service", "", "Service in front of the webhook command as namespace/name, v1beta1 AppArmorProfile is only served and objects are only validated on admission with the webhook")
+	initCmd.Flags().Int32Var(&webhookServicePort, "webhook-service-port", 443, "Port of the webhook service")
+	initCmd.Flags().StringVar(&webhookCABundle, "webhook-ca-bundle", "", "PEM file of the CA the webhook serving certificate is signed by")
+	initCmd.Flags().BoolVar(&validatePods, "validate-pods", false, "Register the webhook checking that the AppArmor profiles of new pods are loaded on their nodes")
+	initCmd.Flags().BoolVar(&mutatePods, "mutate-pods", false, "Register the webhook assigning the profiles of the AppArmorProfileBinding objects to new pods")
+
+	var webhookCmd = &cobra.Command{
+		Use:   "webhook",
+		Short: "Serve the AppArmorProfile conversion, validating and pod webhooks",
+		Long:  "Serve the webhooks converting AppArmorProfile objects between v1alpha1 and v1beta1, refusing invalid objects, checking the profiles of new pods and assigning the profiles of AppArmorProfileBinding objects to them, it is meant to run in the cluster behind the service passed to init --webhook-service",
+		Run: func(cmd *cobra.Command, args []string) {
+			server := webhook.NewServer()
+
+			k8s, err := appArmor.KubeClient()
+			if err != nil {
+				log.Fatalf("webhook error: %v", err)
+			}
+
+			server.EnableFragmentCheck(k8s)
+
+			if podCheck != "off" {
+				// the pods are checked against cached objects, an admission doesn't read the cluster
+				cache, err := k8s.NewCache(make(chan struct{}))
+				if err != nil {
+					log.Fatalf("webhook error: %v", err)
+				}
+
+				err = server.EnablePodCheck(cache, podCheck)
+				if err != nil {
+					log.Fatalf("webhook error: %v", err)
+				}
+			}
+
+			if profileInjection != "off" {
+				err = server.EnableProfileBinding(k8s, profileInjection)
+				if err != nil {
+					log.Fatalf("webhook error: %v", err)
+				}
+			}
+
+			err = server.Run(fmt.Sprintf(":%d", webhookPort), tlsCertFile, tlsKeyFile)
+			if err != nil {
+				log.Fatalf("webhook error: %v", err)
+			}
+		},
+	}
+
+	webhookCmd.Flags().IntVar(&webhookPort, "port", 8443, "Port to serve the webhook on")
+	webhookCmd.Flags().StringVar(&podCheck, "pod-check", webhook.PodCheckWarn, "What to do with pods using AppArmor profiles which are not loaded on their nodes: warn, deny or off")
+	webhookCmd.Flags().StringVar(&profileInjection, "profile-injection", webhook.InjectAnnotation, "How profiles are assigned to the pods selected by AppArmorProfileBinding objects: annotation, field (Kubernetes 1.30+) or off")
+	webhookCmd.Flags().StringVar(&tlsCertFile, "tls-cert-file", "", "PEM file of the serving certificate")
+	webhookCmd.Flags().StringVar(&tlsKeyFile, "tls-private-key-file", "", "PEM file of the serving certificate private key")
+	webhookCmd.MarkFlagRequired("tls-cert-file")
+	webhookCmd.MarkFlagRequired("tls-private-key-file")
+
+	var migrateFiles []string
+	var migrateInPlace bool
+
+	var migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite workload manifests from AppArmor annotations to securityContext.appArmorProfile fields",
+		Long:  "Rewrite the container.apparmor.security.beta.kubernetes.io annotations of the pods and pod templates of YAML manifests to the securityContext.appArmorProfile fields of the containers, supported by Kubernetes 1.30+. The manifests are printed unless --in-place is set, comments are not kept.",
+		Run: func(cmd *cobra.Command, args []string) {
+			err := migrateManifests(migrateFiles, migrateInPlace)
+			if err != nil {
+				log.Fatalf("migrate error: %v", err)
+			}
+		},
+	}
+
+	migrateCmd.Flags().StringSliceVarP(&migrateFiles, "filename", "f", nil, "Manifest files or directories to rewrite")
+	migrateCmd.Flags().BoolVar(&migrateInPlace, "in-place", false, "Write the rewritten manifests back to the files which changed")
+	migrateCmd.MarkFlagRequired("filename")
+
+	var lintFormat, lintFailOn string
+
+	var lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Check AppArmor profiles against security best practices",
+		Long:  "Check the rules of the AppArmorProfile and AppArmorProfileFragment objects for risky patterns, e.g. paths written and executed, dangerous capabilities, unrestricted mount or raw sockets. Checks are ignored per object with the " + lint.IgnoreAnnotation + " annotation, listing their IDs separated by commas.",
+		Run: func(cmd *cobra.Command, args []string) {
+			threshold, err := lint.ParseSeverity(lintFailOn)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			findings, err := appArmor.Lint()
+			if err != nil {
+				log.Fatalf("lint error: %v", err)
+			}
+
+			err = findings.Write(os.Stdout, lintFormat)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if findings.Failed(threshold) {
+				os.Exit(1)
+			}
+		},
+	}
+
+	lintCmd.Flags().StringSliceVarP(&profileFiles, "filename", "f", nil, "Read AppArmorProfile and AppArmorProfileFragment objects from local files or directories instead of the cluster")
+	lintCmd.Flags().StringVarP(&lintFormat, "output", "o", lint.FormatText, "Output format (text, json, sarif)")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", string(lint.SeverityError), "Exit with status 1 if a finding is at least this severe (error, warning, info)")
+
+	var fmtFiles []string
+	var fmtCheck bool
+
+	var fmtCmd = &cobra.Command{
+		Use:   "fmt",
+		Short: "Format the rules of AppArmorProfile manifests",
+		Long:  "Rewrite the rules and profile texts of the AppArmorProfile and AppArmorProfileFragment objects of YAML manifests in the canonical format: one rule per line ending with a comma, tab indentation, ordered permissions, and the rules of every section sorted and deduplicated. Comments and blank lines are kept. The files are rewritten in place unless --check is set.",
+		Run: func(cmd *cobra.Command, args []string) {
+			formatted, err := formatManifests(fmtFiles, fmtCheck)
+			if err != nil {
+				log.Fatalf("fmt error: %v", err)
+			}
+
+			if fmtCheck && !formatted {
+				os.Exit(1)
+			}
+		},
+	}
+
+	fmtCmd.Flags().StringSliceVarP(&fmtFiles, "filename", "f", nil, "Manifest files or directories to format")
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "List the files which are not formatted and exit with status 1 if there is any, instead of rewriting them")
+	fmtCmd.MarkFlagRequired("filename")
+
+	var renderOptions aa.RenderOptions
+
+	var renderCmd = &cobra.Command{
+		Use:   "render [name...]",
+		Short: "Print the AppArmor profiles as they are deployed on worker nodes",
+		Long:  "Print the files sync deploys on worker nodes for the AppArmorProfile objects of the cluster, or of local files with -f, all of them if no name is given. With --check the profiles are compiled with the local apparmor_parser, without loading them, if it is installed.",
+		Run: func(cmd *cobra.Command, args []string) {
+			renderOptions.Names = args
+
+			profiles, err := appArmor.Render(renderOptions)
+			if err != nil {
+				log.Fatalf("render error: %v", err)
+			}
+
+			failed := false
+
+			for i, profile := range profiles {
+				if i > 0 {
+					fmt.Println()
+				}
+
+				fmt.Print(profile.Text)
+
+				if profile.Mode == v1beta1.ModeDisable {
+					log.Warnf("profile %s is disabled, sync removes it from the nodes", profile.Name)
+				}
+
+				if profile.CheckErr != nil {
+					log.Errorf("profile %s: %v", profile.Name, profile.CheckErr)
+					failed = true
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	renderCmd.Flags().StringSliceVarP(&profileFiles, "filename", "f", nil, "Read AppArmorProfile and AppArmorProfileFragment objects from local files or directories instead of the cluster")
+	renderCmd.Flags().BoolVar(&renderOptions.Check, "check", false, "Compile the profiles with the local apparmor_parser, without loading them")
+
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check whether worker nodes are ready to enforce AppArmor profiles",
+		Long:  "Check the kernel LSM list, AppArmor module parameters, securityfs, AppArmor tools, container runtime, free space and sudo rights on worker nodes",
+		Run: func(cmd *cobra.Command, args []string) {
+			nodes, findings, err := appArmor.Doctor()
+			if err != nil {
+				log.Fatalf("doctor error: %v", err)
+			}
+
+			findings.PrintFindings()
+			nodes.PrintSkipped()
+
+			if findings.Failed() {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var uninstallCmd = &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the AppArmor profiles from worker nodes and the CRD from the cluster",
+		Long:  "Unload and delete the AppArmor profiles created by the manager on worker nodes, optionally revert the kernel command line change made by init, and delete the CRD along with the AppArmorProfile objects",
+		Run: func(cmd *cobra.Command, args []string) {
+			if !uninstallOptions.KeepCRD && !assumeYes && !nodeFilter.Restricts() {
+				if !confirm("Delete the AppArmorProfile CRD and all the AppArmorProfile objects?") {
+					log.Info("Keeping the CRD")
+					uninstallOptions.KeepCRD = true
+				}
+			}
+
+			nodes, err := appArmor.Uninstall(uninstallOptions)
+			nodes.PrintSkipped()
+			if err != nil {
+				log.Fatalf("uninstall error: %v", err)
+			}
+		},
+	}
+
+	uninstallCmd.Flags().BoolVar(&uninstallOptions.KeepCRD, "keep-crd", false, "Keep the CRD and the AppArmorProfile objects in the cluster, required along with --nodes, --exclude-nodes or -l")
+	uninstallCmd.Flags().BoolVar(&uninstallOptions.RemoveUnmanaged, "remove-unmanaged", false, "Also remove the profiles named after an AppArmorProfile object which don't have the managed header")
+	uninstallCmd.Flags().BoolVar(&uninstallOptions.RevertKernelCmdline, "revert-kernel-cmdline", false, "Restore the boot configuration backed up by init, it takes effect at the next restart")
+	uninstallCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Delete the CRD without asking for confirmation")
+	uninstallCmd.Flags().StringSliceVarP(&profileFiles, "filename", "f", nil, "Read the objects --remove-unmanaged looks for from local files or directories instead of the cluster")
+
+	syncCmd.Flags().StringSliceVarP(&profileFiles, "filename", "f", nil, "Read AppArmorProfile and AppArmorProfileFragment objects from local files or directories instead of the cluster")
+	syncCmd.Flags().BoolVar(&syncOptions.Force, "force", false, "Redeploy all the profiles and fragments, even those recorded as up to date on the nodes")
+	syncCmd.Flags().BoolVar(&syncOptions.DeleteInUse, "delete-in-use", false, "Remove the profiles of deleted objects even though running pods use them")
+
+	for _, cmd := range []*cobra.Command{initCmd, syncCmd, enforcedCmd, enabledCmd, doctorCmd, uninstallCmd} {
+		addNodeFilterFlags(cmd, &nodeFilter)
+	}
+
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(enforcedCmd)
 	rootCmd.AddCommand(enabledCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(uninstallCmd)
+	rootCmd.AddCommand(webhookCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(fmtCmd)
+	rootCmd.AddCommand(renderCmd)
 
 	rootCmd.Execute()
 }
@@ -123,6 +419,147 @@ const (
 	kubectlBinary = "apparmor-manager"
 )
 
+// confirm asks a yes/no question on the terminal, anything but yes is a no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// migrateManifests rewrites the AppArmor annotations of manifests to fields, the manifests are printed or written back
+func migrateManifests(paths []string, inPlace bool) error {
+	for _, path := range paths {
+		files, err := client.ManifestFiles(path)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			out, migration, err := workload.MigrateManifest(data)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite %s: %v", file, err)
+			}
+
+			for _, warning := range migration.Warnings {
+				log.Warnf("%s: %s, the annotation is kept", file, warning)
+			}
+
+			if !inPlace {
+				fmt.Printf("# %s\n%s", file, out)
+				continue
+			}
+
+			if migration.Migrated == 0 {
+				continue
+			}
+
+			err = ioutil.WriteFile(file, out, 0644)
+			if err != nil {
+				return err
+			}
+
+			log.Infof("%s: %d annotations replaced", file, migration.Migrated)
+		}
+	}
+
+	return nil
+}
+
+// formatManifests formats the profile rules of manifests in place, or only lists the files to format if check is set.
+// It returns whether all the files are formatted already.
+func formatManifests(paths []string, check bool) (bool, error) {
+	formatted := true
+
+	for _, path := range paths {
+		files, err := client.ManifestFiles(path)
+		if err != nil {
+			return false, err
+		}
+
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return false, err
+			}
+
+			out, formatting, err := client.FormatManifest(data)
+			if err != nil {
+				return false, fmt.Errorf("failed to format %s: %v", file, err)
+			}
+
+			for _, warning := range formatting.Warnings {
+				log.Warnf("%s: %s", file, warning)
+			}
+
+			if len(formatting.Changed) == 0 {
+				continue
+			}
+
+			formatted = false
+
+			if check {
+				fmt.Printf("%s: %s\n", file, strings.Join(formatting.Changed, ", "))
+				continue
+			}
+
+			err = ioutil.WriteFile(file, out, 0644)
+			if err != nil {
+				return false, err
+			}
+
+			log.Infof("%s: formatted %s", file, strings.Join(formatting.Changed, ", "))
+		}
+	}
+
+	return formatted, nil
+}
+
+// newWebhookConfig returns the conversion webhook configuration of the CRD, nil if no webhook service is set
+func newWebhookConfig(service string, port int32, caBundleFile string) (*crd.WebhookConfig, error) {
+	if service == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(service, "/")
+
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid webhook service %q, expected namespace/name", service)
+	}
+
+	config := &crd.WebhookConfig{
+		Namespace: parts[0],
+		Name:      parts[1],
+		Port:      port,
+	}
+
+	if caBundleFile != "" {
+		caBundle, err := ioutil.ReadFile(caBundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the webhook CA bundle: %v", err)
+		}
+
+		config.CABundle = caBundle
+	}
+
+	return config, nil
+}
+
+// addNodeFilterFlags adds the node selection flags to a command
+func addNodeFilterFlags(cmd *cobra.Command, filter *types.NodeFilter) {
+	cmd.Flags().StringVarP(&filter.Selector, "selector", "l", "", "Label selector to filter nodes, e.g. pool=frontend")
+	cmd.Flags().StringSliceVar(&filter.Nodes, "nodes", nil, "Comma separated list of node names to operate on")
+	cmd.Flags().StringSliceVar(&filter.ExcludeNodes, "exclude-nodes", nil, "Comma separated list of node names to skip")
+	cmd.Flags().BoolVar(&filter.IncludeSkipped, "include-skipped", false, "Operate on NotReady and cordoned nodes instead of skipping them")
+}
+
 func getBinary(arg string) string {
 	_, binary := filepath.Split(arg)
 
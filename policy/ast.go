@@ -0,0 +1,237 @@
+// Package policy parses and renders the AppArmor policy language. The AST keeps the comments and the
+// line structure of the source, so that rendering, validation and linting work from the same tree.
+package policy
+
+// Node is a node of the policy AST
+type Node interface {
+	// Pos returns the position of the first token of the node, the zero position for a generated node
+	Pos() Position
+	// End returns the position of the last token of the node
+	End() Position
+}
+
+// span is the source range of a node
+type span struct {
+	Start Position
+	Stop  Position
+}
+
+// Pos returns the position of the first token of the node
+func (s span) Pos() Position {
+	return s.Start
+}
+
+// End returns the position of the last token of the node
+func (s span) End() Position {
+	return s.Stop
+}
+
+// Policy is a policy file, the preamble (abi, includes, variables, aliases) followed by profiles
+type Policy struct {
+	span
+	Nodes []Node
+}
+
+// Profiles returns the top level profiles of the policy
+func (p *Policy) Profiles() []*Profile {
+	profiles := []*Profile{}
+
+	for _, n := range p.Nodes {
+		if profile, ok := n.(*Profile); ok {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
+
+// Profile returns the top level profile with the given name, nil if there is none
+func (p *Policy) Profile(name string) *Profile {
+	for _, profile := range p.Profiles() {
+		if profile.Name == name {
+			return profile
+		}
+	}
+
+	return nil
+}
+
+// CommentNode is a comment, Trailing comments follow a statement on the same line
+type CommentNode struct {
+	span
+	Text     string
+	Trailing bool
+}
+
+// ABI is an abi statement, e.g. abi <abi/3.0>,
+type ABI struct {
+	span
+	Path Path
+}
+
+// Include is an include statement, e.g. include <tunables/global>
+type Include struct {
+	span
+	Path Path
+	// IfExists doesn't fail if the included file is missing
+	IfExists bool
+	// Legacy is the #include syntax
+	Legacy bool
+}
+
+// Path is the path of an abi or include statement, Magic paths (<...>) are relative to the policy directory
+type Path struct {
+	Value string
+	Magic bool
+}
+
+// Variable is a variable assignment, e.g. @{HOME} = /home/*/ /root/
+type Variable struct {
+	span
+	Name string
+	// Append is the += operator
+	Append bool
+	Values []string
+}
+
+// Alias is an alias rule, e.g. alias /usr/ -> /mnt/usr/,
+type Alias struct {
+	span
+	From string
+	To   string
+}
+
+// Profile is a profile or a hat with its rules
+type Profile struct {
+	span
+	// Keyword is profile or hat, empty for the ^hat and the attachment only syntax
+	Keyword string
+	// Name is the profile name, the attachment if the profile is declared by its attachment only
+	Name string
+	// Hat is a ^hat
+	Hat bool
+	// Attachment is the executable path glob of a named profile
+	Attachment string
+	Flags      []string
+	// Body contains rules, includes, comments and nested profiles
+	Body []Node
+	// Qualifiers of the profile declaration, e.g. audit
+	Qualifiers Qualifiers
+}
+
+// HasFlag checks whether the profile has a flag
+func (p *Profile) HasFlag(flag string) bool {
+	for _, f := range p.Flags {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Qualifiers are the rule prefixes changing how a rule applies
+type Qualifiers struct {
+	Audit bool
+	Deny  bool
+	Allow bool
+	Quiet bool
+	Owner bool
+	// Priority is the priority=N prefix of AppArmor 4, empty if not set
+	Priority string
+}
+
+// Rule is a rule ending with a comma
+type Rule interface {
+	Node
+	// Kind returns the rule keyword, e.g. capability, or file for a path rule
+	Kind() string
+	// Qualifier returns the rule qualifiers
+	Qualifier() Qualifiers
+	// String returns the rule without the comma
+	String() string
+}
+
+// FileRule is a file rule, e.g. owner /tmp/** rw, or /usr/bin/foo Px -> foo,
+type FileRule struct {
+	span
+	Qualifiers
+	// Keyword is the optional file keyword
+	Keyword bool
+	// Path is the path glob, empty for a bare file rule which allows every file
+	Path string
+	// Perms are the permissions, e.g. rw or ix
+	Perms string
+	// PermsFirst is the permissions before the path syntax, e.g. r /etc/**,
+	PermsFirst bool
+	// Target is the transition target of an exec rule or the link target
+	Target string
+}
+
+// CapabilityRule is a capability rule, a rule without capabilities allows all of them
+type CapabilityRule struct {
+	span
+	Qualifiers
+	Capabilities []string
+}
+
+// NetworkRule is a network rule, e.g. network inet stream,
+type NetworkRule struct {
+	span
+	Qualifiers
+	// Args are the domain, type and protocol, a rule without arguments allows all network access
+	Args []string
+}
+
+// ChangeProfileRule is a change_profile rule, e.g. change_profile /bin/foo -> bar,
+type ChangeProfileRule struct {
+	span
+	Qualifiers
+	// Mode is safe or unsafe, empty if not set
+	Mode   string
+	Exec   string
+	Target string
+}
+
+// Conditional is a conditional of a mediation rule, e.g. peer=foo or set=(hup, int)
+type Conditional struct {
+	Key    string
+	Values []string
+	// List is the parenthesized syntax
+	List bool
+}
+
+// MediationRule is a rule of the other mediation classes: signal, ptrace, mount, umount, remount,
+// pivot_root, dbus, unix, set rlimit, userns, mqueue, io_uring, link and all
+type MediationRule struct {
+	span
+	Qualifiers
+	Keyword string
+	// Access are the permissions, e.g. (send, receive)
+	Access []string
+	// AccessList is the parenthesized access syntax
+	AccessList bool
+	Conds      []Conditional
+	// Args are the positional arguments, e.g. the mount source
+	Args []string
+	// Target follows ->, e.g. the mount point
+	Target string
+}
+
+// Kind returns file
+func (r *FileRule) Kind() string { return "file" }
+
+// Kind returns capability
+func (r *CapabilityRule) Kind() string { return "capability" }
+
+// Kind returns network
+func (r *NetworkRule) Kind() string { return "network" }
+
+// Kind returns change_profile
+func (r *ChangeProfileRule) Kind() string { return "change_profile" }
+
+// Kind returns the rule keyword
+func (r *MediationRule) Kind() string { return r.Keyword }
+
+// Qualifier returns the rule qualifiers
+func (q Qualifiers) Qualifier() Qualifiers { return q }
@@ -0,0 +1,234 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// permsOrder is the order of the access permissions, the exec permissions follow them as written
+const permsOrder = "mrwalk"
+
+// kindRanks order the rules of a section by kind, the mediation rules are ordered by keyword between them
+var kindRanks = map[string]int{
+	"capability":     0,
+	"network":        1,
+	"change_profile": 3,
+	"file":           4,
+}
+
+// FormatRules renders the rules of a profile body in the canonical format, see Canonicalize
+func FormatRules(src string) (string, error) {
+	nodes, err := ParseRules(src)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Format(Canonicalize(nodes), 0), nil
+}
+
+// FormatPolicy renders a policy file in the canonical format, see Canonicalize
+func FormatPolicy(src string) (string, error) {
+	pol, err := Parse(src)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Format(Canonicalize(pol.Nodes), 0), nil
+}
+
+// Canonicalize orders the permissions of the file rules, then sorts and dedupes the rules of every section of
+// a body, nested profiles included. A section is a run of rules, it ends at a blank line, a comment or any other
+// statement, which stay in place. A trailing comment moves along with its rule.
+func Canonicalize(nodes []Node) []Node {
+	out := []Node{}
+	section := []ruleUnit{}
+
+	flush := func() {
+		out = append(out, sortSection(section)...)
+		section = nil
+	}
+
+	for i := 0; i < len(nodes); i++ {
+		switch n := nodes[i].(type) {
+		case Rule:
+			if len(section) > 0 && n.Pos().Line > section[len(section)-1].end().Line+1 {
+				flush()
+			}
+
+			unit := ruleUnit{rule: canonicalRule(n)}
+
+			if i+1 < len(nodes) {
+				if c, ok := nodes[i+1].(*CommentNode); ok && c.Trailing {
+					unit.comment = c
+					i++
+				}
+			}
+
+			section = append(section, unit)
+		case *Profile:
+			flush()
+			n.Body = Canonicalize(n.Body)
+			out = append(out, n)
+		default:
+			flush()
+			out = append(out, n)
+		}
+	}
+
+	flush()
+
+	return out
+}
+
+// ruleUnit is a rule with its trailing comment
+type ruleUnit struct {
+	rule    Rule
+	comment *CommentNode
+}
+
+func (u ruleUnit) end() Position {
+	if u.comment != nil {
+		return u.comment.End()
+	}
+
+	return u.rule.End()
+}
+
+// sortSection sorts and dedupes the rules of a section, they are given consecutive lines within the lines of the
+// section so that the printer keeps the blank lines around it only
+func sortSection(section []ruleUnit) []Node {
+	if len(section) == 0 {
+		return nil
+	}
+
+	start := section[0].rule.Pos()
+	end := section[len(section)-1].end()
+
+	sort.SliceStable(section, func(i, j int) bool {
+		return ruleKey(section[i].rule) < ruleKey(section[j].rule)
+	})
+
+	units := []ruleUnit{}
+
+	for _, u := range section {
+		if len(units) > 0 {
+			last := &units[len(units)-1]
+
+			if last.rule.String() == u.rule.String() && (last.comment == nil || u.comment == nil) {
+				if last.comment == nil {
+					last.comment = u.comment
+				}
+
+				continue
+			}
+		}
+
+		units = append(units, u)
+	}
+
+	nodes := []Node{}
+
+	for i, u := range units {
+		s := span{Start: Position{Line: start.Line + i}, Stop: Position{Line: start.Line + i}}
+
+		if i == 0 {
+			s.Start = start
+		}
+
+		if i == len(units)-1 {
+			s.Stop = end
+		}
+
+		nodes = append(nodes, withSpan(u.rule, s))
+
+		if u.comment != nil {
+			nodes = append(nodes, &CommentNode{span: s, Text: u.comment.Text, Trailing: true})
+		}
+	}
+
+	return nodes
+}
+
+// ruleKey orders the rules by kind, then by path or arguments
+func ruleKey(r Rule) string {
+	rank, ok := kindRanks[r.Kind()]
+
+	if !ok {
+		rank = 2
+	}
+
+	key := string(rune('0'+rank)) + " " + r.Kind() + " "
+
+	if f, ok := r.(*FileRule); ok {
+		key += f.Path + " "
+	}
+
+	return key + strings.TrimPrefix(r.String(), r.Qualifier().String())
+}
+
+// canonicalRule returns a file rule with its permissions in order and without repeated letters, the other rules
+// as they are
+func canonicalRule(r Rule) Rule {
+	f, ok := r.(*FileRule)
+
+	if !ok {
+		return r
+	}
+
+	ret := *f
+	ret.Perms = canonicalPerms(f.Perms)
+
+	return &ret
+}
+
+// canonicalPerms orders the access permissions, the exec permissions are kept as written after them since their
+// letters combine, e.g. Pix
+func canonicalPerms(perms string) string {
+	access := ""
+
+	for _, c := range permsOrder {
+		if strings.ContainsRune(perms, c) {
+			access += string(c)
+		}
+	}
+
+	exec := ""
+
+	for _, c := range perms {
+		if !strings.ContainsRune(permsOrder, c) && !strings.ContainsRune(exec, c) {
+			exec += string(c)
+		}
+	}
+
+	return access + exec
+}
+
+// withSpan returns a copy of a rule with another span
+func withSpan(r Rule, s span) Rule {
+	switch r := r.(type) {
+	case *FileRule:
+		ret := *r
+		ret.span = s
+		return &ret
+	case *CapabilityRule:
+		ret := *r
+		ret.span = s
+		return &ret
+	case *NetworkRule:
+		ret := *r
+		ret.span = s
+		return &ret
+	case *ChangeProfileRule:
+		ret := *r
+		ret.span = s
+		return &ret
+	case *MediationRule:
+		ret := *r
+		ret.span = s
+		return &ret
+	}
+
+	return r
+}
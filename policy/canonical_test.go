@@ -0,0 +1,106 @@
+package policy
+
+import "testing"
+
+func TestFormatRules(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "rules sorted by kind then path",
+			src:  "/tmp/** rw,\n/etc/** r,\ncapability net_raw,\n",
+			want: "capability net_raw,\n/etc/** r,\n/tmp/** rw,\n",
+		},
+		{
+			name: "mediation rules between network and change_profile",
+			src:  "signal,\nnetwork,\ncapability,\n/a r,\nptrace,\nchange_profile -> foo,\n",
+			want: "capability,\nnetwork,\nptrace,\nsignal,\nchange_profile -> foo,\n/a r,\n",
+		},
+		{
+			name: "trailing comment moves with its rule",
+			src:  "# header\n/tmp/** rw,\n/etc/** r, # etc\n",
+			want: "# header\n/etc/** r, # etc\n/tmp/** rw,\n",
+		},
+		{
+			name: "blank line ends a section",
+			src:  "/d r,\n/c r,\n\n/b r,\n/a r,\n",
+			want: "/c r,\n/d r,\n\n/a r,\n/b r,\n",
+		},
+		{
+			name: "comment line ends a section",
+			src:  "/b r,\n# middle\n/a r,\n",
+			want: "/b r,\n# middle\n/a r,\n",
+		},
+		{
+			name: "duplicates removed, the comment is kept",
+			src:  "/a r,\n/a r,\n/a r, # dup\n",
+			want: "/a r, # dup\n",
+		},
+		{
+			name: "qualifiers sort after the plain rule",
+			src:  "deny /a w,\n/a r,\naudit /a r,\n",
+			want: "/a r,\naudit /a r,\ndeny /a w,\n",
+		},
+		{
+			name: "nested profile sorted on its own",
+			src:  "profile sub {\n  /z r,\n  /a r,\n}\n/y r,\n/b r,\n",
+			want: "profile sub {\n\t/a r,\n\t/z r,\n}\n/b r,\n/y r,\n",
+		},
+		{
+			name: "permissions in order",
+			src:  "/tmp/** wr,\n/x Cxmr,\n",
+			want: "/tmp/** rw,\n/x mrCx,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatRules(tt.src)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+
+			again, err := FormatRules(got)
+
+			if err != nil || again != got {
+				t.Errorf("formatting is not idempotent: %q, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalPerms(t *testing.T) {
+	tests := []struct {
+		perms string
+		want  string
+	}{
+		{"r", "r"},
+		{"wr", "rw"},
+		{"kalwrm", "mrwalk"},
+		{"rrww", "rw"},
+		{"Pix", "Pix"},
+		{"mrPix", "mrPix"},
+		{"rPix", "rPix"},
+		{"Pixr", "rPix"},
+		{"PixPix", "Pix"},
+		{"Cx", "Cx"},
+		{"xCmr", "mrxC"},
+		{"Cxmr", "mrCx"},
+		{"ixP", "ixP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.perms, func(t *testing.T) {
+			if got := canonicalPerms(tt.perms); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
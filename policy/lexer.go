@@ -0,0 +1,266 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenType is the type of a lexical token
+type TokenType int
+
+const (
+	// EOF ends the token stream
+	EOF TokenType = iota
+	// Word is an unquoted word, e.g. a keyword, a path glob, a permission or a conditional
+	Word
+	// String is a quoted string, the value is unquoted
+	String
+	// Comment is a comment, the value doesn't contain the leading #
+	Comment
+	// Comma ends a rule
+	Comma
+	// LBrace starts a block
+	LBrace
+	// RBrace ends a block
+	RBrace
+	// LParen starts a list
+	LParen
+	// RParen ends a list
+	RParen
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case EOF:
+		return "end of input"
+	case Word:
+		return "word"
+	case String:
+		return "string"
+	case Comment:
+		return "comment"
+	case Comma:
+		return "','"
+	case LBrace:
+		return "'{'"
+	case RBrace:
+		return "'}'"
+	case LParen:
+		return "'('"
+	case RParen:
+		return "')'"
+	}
+
+	return "unknown token"
+}
+
+// Position is a position in the policy text, lines and columns start at 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is a lexical token
+type Token struct {
+	Type  TokenType
+	Value string
+	Pos   Position
+}
+
+// Error is a syntax error at a position of the policy text
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %s: %s", e.Pos, e.Msg)
+}
+
+// Lex splits a policy text into tokens, the last token is EOF
+func Lex(src string) ([]Token, error) {
+	l := &lexer{src: src, line: 1, column: 1}
+	tokens := []Token{}
+
+	for {
+		t, err := l.next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+
+		if t.Type == EOF {
+			return tokens, nil
+		}
+	}
+}
+
+type lexer struct {
+	src    string
+	offset int
+	line   int
+	column int
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.offset+n >= len(l.src) {
+		return 0
+	}
+
+	return l.src[l.offset+n]
+}
+
+func (l *lexer) advance() byte {
+	c := l.src[l.offset]
+	l.offset++
+
+	if c == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
+	return c
+}
+
+func (l *lexer) pos() Position {
+	return Position{Line: l.line, Column: l.column}
+}
+
+func (l *lexer) next() (Token, error) {
+	for l.offset < len(l.src) && isSpace(l.peek(0)) {
+		l.advance()
+	}
+
+	pos := l.pos()
+
+	if l.offset >= len(l.src) {
+		return Token{Type: EOF, Pos: pos}, nil
+	}
+
+	c := l.peek(0)
+
+	switch {
+	case c == '#' && strings.HasPrefix(l.src[l.offset:], "#include") && !isWordChar(l.peek(len("#include"))):
+		// #include is the old include syntax, not a comment
+		for i := 0; i < len("#include"); i++ {
+			l.advance()
+		}
+
+		return Token{Type: Word, Value: "#include", Pos: pos}, nil
+	case c == '#':
+		l.advance()
+		start := l.offset
+
+		for l.offset < len(l.src) && l.peek(0) != '\n' {
+			l.advance()
+		}
+
+		return Token{Type: Comment, Value: strings.TrimRight(l.src[start:l.offset], " \t\r"), Pos: pos}, nil
+	case c == '"':
+		return l.quoted(pos)
+	case c == ',':
+		l.advance()
+		return Token{Type: Comma, Value: ",", Pos: pos}, nil
+	case c == '{' && !l.alternation():
+		l.advance()
+		return Token{Type: LBrace, Value: "{", Pos: pos}, nil
+	case c == '}':
+		l.advance()
+		return Token{Type: RBrace, Value: "}", Pos: pos}, nil
+	case c == '(':
+		l.advance()
+		return Token{Type: LParen, Value: "(", Pos: pos}, nil
+	case c == ')':
+		l.advance()
+		return Token{Type: RParen, Value: ")", Pos: pos}, nil
+	}
+
+	return l.word(pos)
+}
+
+// alternation checks whether the brace at the current offset starts a glob alternation, e.g. {a,b},
+// rather than a block, a block brace is followed by a space or closed right away
+func (l *lexer) alternation() bool {
+	next := l.peek(1)
+
+	return next != 0 && !isSpace(next) && next != '}'
+}
+
+func (l *lexer) quoted(pos Position) (Token, error) {
+	l.advance()
+
+	value := strings.Builder{}
+
+	for {
+		if l.offset >= len(l.src) {
+			return Token{}, &Error{Pos: pos, Msg: "unterminated string"}
+		}
+
+		c := l.advance()
+
+		switch c {
+		case '"':
+			return Token{Type: String, Value: value.String(), Pos: pos}, nil
+		case '\\':
+			if l.offset < len(l.src) {
+				value.WriteByte(c)
+				c = l.advance()
+			}
+		}
+
+		value.WriteByte(c)
+	}
+}
+
+// word lexes an unquoted word, braces of variables (@{HOME}) and alternations (/usr/{bin,sbin}) are part of it
+func (l *lexer) word(pos Position) (Token, error) {
+	start := l.offset
+	depth := 0
+
+	for l.offset < len(l.src) {
+		c := l.peek(0)
+
+		if c == '{' {
+			depth++
+		} else if c == '}' {
+			if depth == 0 {
+				break
+			}
+
+			depth--
+		} else if depth == 0 && !isWordChar(c) {
+			break
+		} else if depth > 0 && isSpace(c) {
+			return Token{}, &Error{Pos: pos, Msg: "unterminated '{' in word"}
+		}
+
+		l.advance()
+	}
+
+	if depth > 0 {
+		return Token{}, &Error{Pos: pos, Msg: "unterminated '{' in word"}
+	}
+
+	return Token{Type: Word, Value: l.src[start:l.offset], Pos: pos}, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordChar(c byte) bool {
+	switch c {
+	case 0, ' ', '\t', '\n', '\r', ',', '(', ')', '"':
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		tokens []string
+		err    string
+	}{
+		{
+			name:   "rule with a trailing comment",
+			src:    "/etc/** r, # read\n",
+			tokens: []string{"word /etc/** 1:1", "word r 1:9", "',' , 1:10", "comment  read 1:12", "end of input  2:1"},
+		},
+		{
+			name: "profile header with an alternation and flags",
+			src:  "profile foo /usr/bin/{a,b} flags=(complain) {\n}\n",
+			tokens: []string{
+				"word profile 1:1", "word foo 1:9", "word /usr/bin/{a,b} 1:13", "word flags= 1:28", "'(' ( 1:34",
+				"word complain 1:35", "')' ) 1:43", "'{' { 1:45", "'}' } 2:1", "end of input  3:1",
+			},
+		},
+		{
+			name:   "variable in a path",
+			src:    "@{HOME}/.ssh/ r,",
+			tokens: []string{"word @{HOME}/.ssh/ 1:1", "word r 1:15", "',' , 1:16", "end of input  1:17"},
+		},
+		{
+			name:   "old include syntax is not a comment",
+			src:    "#include <tunables/global>",
+			tokens: []string{"word #include 1:1", "word <tunables/global> 1:10", "end of input  1:27"},
+		},
+		{
+			name: "quoted string",
+			src:  `signal (send) peer="a b",`,
+			tokens: []string{
+				"word signal 1:1", "'(' ( 1:8", "word send 1:9", "')' ) 1:13", "word peer= 1:15", "string a b 1:20",
+				"',' , 1:25", "end of input  1:26",
+			},
+		},
+		{
+			name:   "escaped quote in a string",
+			src:    `"/tmp/a\"b" r,`,
+			tokens: []string{`string /tmp/a\"b 1:1`, "word r 1:13", "',' , 1:14", "end of input  1:15"},
+		},
+		{
+			name: "unterminated alternation",
+			src:  "/tmp/{a r,",
+			err:  "line 1:1: unterminated '{' in word",
+		},
+		{
+			name: "unterminated string",
+			src:  `"abc`,
+			err:  "line 1:1: unterminated string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Lex(tt.src)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := []string{}
+
+			for _, token := range tokens {
+				got = append(got, fmt.Sprintf("%s %s %s", token.Type, token.Value, token.Pos))
+			}
+
+			if !reflect.DeepEqual(got, tt.tokens) {
+				t.Errorf("expected tokens %q, got %q", tt.tokens, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,769 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var permsRegexp = regexp.MustCompile(`^[rwaxlkmiuUpPcCD]+$`)
+
+// mediationKeywords are the keywords of the rules parsed as MediationRule, set starts set rlimit rules
+var mediationKeywords = map[string]bool{
+	"signal":     true,
+	"ptrace":     true,
+	"mount":      true,
+	"umount":     true,
+	"remount":    true,
+	"pivot_root": true,
+	"dbus":       true,
+	"unix":       true,
+	"set":        true,
+	"userns":     true,
+	"mqueue":     true,
+	"io_uring":   true,
+	"link":       true,
+	"all":        true,
+}
+
+// Parse parses a policy file
+func Parse(src string) (*Policy, error) {
+	p, err := newParser(src, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := p.parseBody(true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Policy{span: span{Start: p.tokens[0].Pos, Stop: p.tokens[len(p.tokens)-1].Pos}, Nodes: nodes}, nil
+}
+
+// ParseRules parses the rules of a profile body. A rule ending a line without a comma is terminated when
+// the next line starts a new rule, as in the rules of AppArmorProfile objects where the comma is optional.
+func ParseRules(src string) ([]Node, error) {
+	p, err := newParser(src, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseBody(true)
+}
+
+type parser struct {
+	tokens []Token
+	i      int
+	// lenient terminates a rule at the end of the line if the comma is missing
+	lenient bool
+}
+
+func newParser(src string, lenient bool) (*parser, error) {
+	tokens, err := Lex(src)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens, lenient: lenient}, nil
+}
+
+func (p *parser) peek(n int) Token {
+	if p.i+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+
+	return p.tokens[p.i+n]
+}
+
+func (p *parser) next() Token {
+	t := p.peek(0)
+
+	if p.i < len(p.tokens)-1 {
+		p.i++
+	}
+
+	return t
+}
+
+// previous returns the last consumed token, nil at the start
+func (p *parser) previous() *Token {
+	if p.i == 0 {
+		return nil
+	}
+
+	return &p.tokens[p.i-1]
+}
+
+func errorf(t Token, format string, args ...interface{}) error {
+	return &Error{Pos: t.Pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseBody parses statements up to the end of the input for the top level, up to the closing brace otherwise
+func (p *parser) parseBody(top bool) ([]Node, error) {
+	nodes := []Node{}
+
+	for {
+		t := p.peek(0)
+
+		switch {
+		case t.Type == EOF:
+			if !top {
+				return nil, errorf(t, "missing '}'")
+			}
+
+			return nodes, nil
+		case t.Type == RBrace:
+			if top {
+				return nil, errorf(t, "unexpected '}'")
+			}
+
+			return nodes, nil
+		case t.Type == Comment:
+			prev := p.previous()
+			p.next()
+			nodes = append(nodes, &CommentNode{
+				span:     span{Start: t.Pos, Stop: t.Pos},
+				Text:     t.Value,
+				Trailing: prev != nil && prev.Pos.Line == t.Pos.Line,
+			})
+			continue
+		}
+
+		n, err := p.parseStatement()
+
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+}
+
+func (p *parser) parseStatement() (Node, error) {
+	t := p.peek(0)
+
+	if t.Type == Word {
+		switch {
+		case t.Value == "include" || t.Value == "#include":
+			return p.parseInclude()
+		case t.Value == "abi":
+			return p.parseABI()
+		case isVariableAssignment(t, p.peek(1)):
+			return p.parseVariable()
+		}
+	}
+
+	tokens, term, err := p.collect()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if term.Type == LBrace {
+		return p.parseProfile(tokens)
+	}
+
+	if tokens[0].Type == Word && tokens[0].Value == "alias" {
+		return parseAlias(tokens, term)
+	}
+
+	return parseRule(tokens, term)
+}
+
+// collect returns the tokens of a statement and the token terminating it, a comma or the brace of a block
+func (p *parser) collect() ([]Token, Token, error) {
+	tokens := []Token{}
+	depth := 0
+
+	for {
+		t := p.peek(0)
+
+		if p.lenient && depth == 0 && len(tokens) > 0 {
+			last := tokens[len(tokens)-1]
+
+			if t.Type == EOF || t.Type == RBrace || t.Type == Comment || (t.Pos.Line > last.Pos.Line && isStatementStart(t)) {
+				// the missing comma is tolerated
+				return tokens, Token{Type: Comma, Value: ",", Pos: last.Pos}, nil
+			}
+		}
+
+		switch t.Type {
+		case EOF, RBrace:
+			if len(tokens) == 0 {
+				return nil, t, errorf(t, "unexpected %s", t.Type)
+			}
+
+			return nil, t, errorf(tokens[len(tokens)-1], "missing ',' after %q", tokens[len(tokens)-1].Value)
+		case Comment:
+			// a comment within a rule spanning several lines
+			p.next()
+			continue
+		case LParen:
+			depth++
+		case RParen:
+			depth--
+
+			if depth < 0 {
+				return nil, t, errorf(t, "unexpected ')'")
+			}
+		case Comma, LBrace:
+			if depth == 0 {
+				p.next()
+
+				if len(tokens) == 0 {
+					return nil, t, errorf(t, "unexpected %s", t.Type)
+				}
+
+				return tokens, t, nil
+			}
+
+			if t.Type == LBrace {
+				return nil, t, errorf(t, "unexpected '{' in a list")
+			}
+		}
+
+		tokens = append(tokens, p.next())
+	}
+}
+
+// isStatementStart checks whether a token can start a statement, used to find the end of a rule without a comma
+func isStatementStart(t Token) bool {
+	switch t.Type {
+	case Comment, String, RBrace, EOF:
+		return true
+	case Word:
+	default:
+		return false
+	}
+
+	switch t.Value {
+	case "audit", "deny", "allow", "quiet", "owner", "include", "#include", "abi", "alias", "profile", "hat", "file",
+		"capability", "network", "change_profile":
+		return true
+	}
+
+	if mediationKeywords[t.Value] {
+		return true
+	}
+
+	return isPath(t.Value) || strings.HasPrefix(t.Value, "^") || strings.HasPrefix(t.Value, "priority=")
+}
+
+func isPath(s string) bool {
+	return strings.HasPrefix(s, "/") || strings.HasPrefix(s, "@{") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "**")
+}
+
+func isVariableAssignment(t, next Token) bool {
+	if !strings.HasPrefix(t.Value, "@{") {
+		return false
+	}
+
+	if strings.Contains(t.Value, "}=") || strings.Contains(t.Value, "}+=") {
+		return true
+	}
+
+	return next.Type == Word && (next.Value == "=" || next.Value == "+=" || strings.HasPrefix(next.Value, "=") || strings.HasPrefix(next.Value, "+="))
+}
+
+func (p *parser) parseInclude() (Node, error) {
+	start := p.next()
+	include := &Include{Legacy: start.Value == "#include"}
+
+	if p.peek(0).Type == Word && p.peek(0).Value == "if" {
+		p.next()
+
+		if p.peek(0).Type != Word || p.peek(0).Value != "exists" {
+			return nil, errorf(p.peek(0), "expected 'exists' after 'include if'")
+		}
+
+		p.next()
+		include.IfExists = true
+	}
+
+	path, err := p.parsePath(start)
+
+	if err != nil {
+		return nil, err
+	}
+
+	include.Path = path
+	include.span = span{Start: start.Pos, Stop: p.previous().Pos}
+
+	return include, nil
+}
+
+func (p *parser) parseABI() (Node, error) {
+	start := p.next()
+	path, err := p.parsePath(start)
+
+	if err != nil {
+		return nil, err
+	}
+
+	last := p.previous()
+
+	if p.peek(0).Type == Comma {
+		last = &p.tokens[p.i]
+		p.next()
+	} else if !p.lenient {
+		return nil, errorf(*last, "missing ',' after abi")
+	}
+
+	return &ABI{span: span{Start: start.Pos, Stop: last.Pos}, Path: path}, nil
+}
+
+// parsePath parses the path of an abi or include statement, on the same line as the keyword
+func (p *parser) parsePath(keyword Token) (Path, error) {
+	t := p.peek(0)
+
+	if t.Pos.Line != keyword.Pos.Line || (t.Type != Word && t.Type != String) {
+		return Path{}, errorf(keyword, "missing path after %s", keyword.Value)
+	}
+
+	p.next()
+
+	if t.Type == String {
+		return Path{Value: t.Value}, nil
+	}
+
+	if strings.HasPrefix(t.Value, "<") {
+		if !strings.HasSuffix(t.Value, ">") || len(t.Value) < 3 {
+			return Path{}, errorf(t, "invalid path %s", t.Value)
+		}
+
+		return Path{Value: t.Value[1 : len(t.Value)-1], Magic: true}, nil
+	}
+
+	return Path{Value: t.Value}, nil
+}
+
+// parseVariable parses a variable assignment, the values end with the line
+func (p *parser) parseVariable() (Node, error) {
+	start := p.next()
+	line := start.Pos.Line
+	rest := []string{}
+
+	name := start.Value
+
+	// @{X}=a is a single word
+	if i := strings.Index(name, "}"); i >= 0 && i < len(name)-1 {
+		rest = append(rest, name[i+1:])
+		name = name[:i+1]
+	}
+
+	for p.peek(0).Pos.Line == line && (p.peek(0).Type == Word || p.peek(0).Type == String) {
+		rest = append(rest, tokenText(p.next()))
+	}
+
+	if len(rest) == 0 {
+		return nil, errorf(start, "missing value of %s", name)
+	}
+
+	v := &Variable{span: span{Start: start.Pos, Stop: p.previous().Pos}, Name: name}
+
+	op := rest[0]
+	rest = rest[1:]
+
+	switch {
+	case strings.HasPrefix(op, "+="):
+		v.Append = true
+		op = op[2:]
+	case strings.HasPrefix(op, "="):
+		op = op[1:]
+	default:
+		return nil, errorf(start, "expected '=' after %s", name)
+	}
+
+	if op != "" {
+		v.Values = append(v.Values, op)
+	}
+
+	v.Values = append(v.Values, rest...)
+
+	if p.peek(0).Type == Comma && p.peek(0).Pos.Line == line {
+		p.next()
+	}
+
+	return v, nil
+}
+
+// parseProfile parses a profile declaration followed by its body
+func (p *parser) parseProfile(tokens []Token) (Node, error) {
+	profile := &Profile{}
+
+	quals, tokens, err := parseQualifiers(tokens)
+
+	if err != nil {
+		return nil, errorf(*p.previous(), "profile without name")
+	}
+
+	profile.Qualifiers = quals
+
+	first := tokens[0]
+
+	switch {
+	case first.Type == Word && (first.Value == "profile" || first.Value == "hat"):
+		profile.Keyword = first.Value
+		profile.Hat = first.Value == "hat"
+		tokens = tokens[1:]
+
+		if len(tokens) == 0 || (tokens[0].Type != Word && tokens[0].Type != String) || isFlags(tokens[0]) {
+			return nil, errorf(first, "missing name after %s", first.Value)
+		}
+
+		profile.Name = tokens[0].Value
+		tokens = tokens[1:]
+
+		if len(tokens) > 0 && (tokens[0].Type == Word || tokens[0].Type == String) && !isFlags(tokens[0]) {
+			profile.Attachment = tokenText(tokens[0])
+			tokens = tokens[1:]
+		}
+	case first.Type == Word && strings.HasPrefix(first.Value, "^"):
+		profile.Hat = true
+		profile.Name = first.Value[1:]
+		tokens = tokens[1:]
+	case first.Type == Word || first.Type == String:
+		profile.Name = first.Value
+		tokens = tokens[1:]
+	default:
+		return nil, errorf(first, "unexpected %s", first.Type)
+	}
+
+	if len(tokens) > 0 && isFlags(tokens[0]) {
+		flags, rest, err := parseFlags(tokens)
+
+		if err != nil {
+			return nil, err
+		}
+
+		profile.Flags = flags
+		tokens = rest
+	}
+
+	if len(tokens) > 0 {
+		return nil, errorf(tokens[0], "unexpected %q in the declaration of profile %s", tokens[0].Value, profile.Name)
+	}
+
+	body, err := p.parseBody(false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end := p.next()
+
+	profile.Body = body
+	profile.span = span{Start: first.Pos, Stop: end.Pos}
+
+	// a profile may end with a comma
+	if p.peek(0).Type == Comma && p.peek(0).Pos.Line == end.Pos.Line {
+		p.next()
+	}
+
+	return profile, nil
+}
+
+func isFlags(t Token) bool {
+	return t.Type == Word && (t.Value == "flags" || strings.HasPrefix(t.Value, "flags="))
+}
+
+// parseFlags parses flags=(a, b), the flags may be separated by commas or spaces
+func parseFlags(tokens []Token) ([]string, []Token, error) {
+	start := tokens[0]
+	tokens = tokens[1:]
+
+	if start.Value == "flags" {
+		if len(tokens) == 0 || tokens[0].Value != "=" {
+			return nil, nil, errorf(start, "expected '=' after flags")
+		}
+
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 || tokens[0].Type != LParen {
+		return nil, nil, errorf(start, "expected '(' after flags=")
+	}
+
+	flags := []string{}
+
+	for i, t := range tokens[1:] {
+		switch t.Type {
+		case RParen:
+			return flags, tokens[i+2:], nil
+		case Word:
+			flags = append(flags, t.Value)
+		case Comma:
+		default:
+			return nil, nil, errorf(t, "unexpected %s in flags", t.Type)
+		}
+	}
+
+	return nil, nil, errorf(start, "missing ')' after flags")
+}
+
+func parseQualifiers(tokens []Token) (Qualifiers, []Token, error) {
+	q := Qualifiers{}
+
+	for len(tokens) > 0 && tokens[0].Type == Word {
+		switch v := tokens[0].Value; {
+		case v == "audit":
+			q.Audit = true
+		case v == "deny":
+			q.Deny = true
+		case v == "allow":
+			q.Allow = true
+		case v == "quiet":
+			q.Quiet = true
+		case v == "owner":
+			q.Owner = true
+		case strings.HasPrefix(v, "priority="):
+			q.Priority = strings.TrimPrefix(v, "priority=")
+		default:
+			return q, tokens, nil
+		}
+
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 {
+		return q, nil, &Error{Msg: "rule without keyword"}
+	}
+
+	return q, tokens, nil
+}
+
+// tokenText returns the text of a word, strings are quoted again
+func tokenText(t Token) string {
+	if t.Type == String {
+		return `"` + t.Value + `"`
+	}
+
+	return t.Value
+}
+
+// parseRule parses the tokens of a rule, the terminating comma excluded
+func parseRule(tokens []Token, term Token) (Rule, error) {
+	start := tokens[0]
+	s := span{Start: start.Pos, Stop: term.Pos}
+
+	quals, tokens, err := parseQualifiers(tokens)
+
+	if err != nil {
+		return nil, errorf(start, "rule without keyword")
+	}
+
+	first := tokens[0]
+
+	if first.Type == Word {
+		switch first.Value {
+		case "capability":
+			r := &CapabilityRule{span: s, Qualifiers: quals}
+
+			for _, t := range tokens[1:] {
+				if t.Type != Word {
+					return nil, errorf(t, "unexpected %s in capability rule", t.Type)
+				}
+
+				r.Capabilities = append(r.Capabilities, t.Value)
+			}
+
+			return r, nil
+		case "network":
+			r := &NetworkRule{span: s, Qualifiers: quals}
+
+			for _, t := range tokens[1:] {
+				if t.Type != Word {
+					return nil, errorf(t, "unexpected %s in network rule", t.Type)
+				}
+
+				r.Args = append(r.Args, t.Value)
+			}
+
+			return r, nil
+		case "change_profile":
+			return parseChangeProfile(s, quals, tokens)
+		case "file":
+			return parseFileRule(s, quals, true, tokens[1:])
+		}
+	}
+
+	if first.Type == String || isPath(first.Value) {
+		return parseFileRule(s, quals, false, tokens)
+	}
+
+	if len(tokens) > 1 && permsRegexp.MatchString(first.Value) && (tokens[1].Type == String || isPath(tokens[1].Value)) {
+		return parseFileRule(s, quals, false, tokens)
+	}
+
+	if first.Type != Word || !mediationKeywords[first.Value] {
+		return nil, errorf(first, "unknown rule %q", first.Value)
+	}
+
+	return parseMediationRule(s, quals, tokens)
+}
+
+func parseAlias(tokens []Token, term Token) (Node, error) {
+	if len(tokens) != 4 || tokens[2].Value != "->" {
+		return nil, errorf(tokens[0], "expected alias <path> -> <path>")
+	}
+
+	return &Alias{span: span{Start: tokens[0].Pos, Stop: term.Pos}, From: tokenText(tokens[1]), To: tokenText(tokens[3])}, nil
+}
+
+func parseFileRule(s span, quals Qualifiers, keyword bool, tokens []Token) (*FileRule, error) {
+	r := &FileRule{span: s, Qualifiers: quals, Keyword: keyword}
+
+	if len(tokens) == 0 {
+		return r, nil
+	}
+
+	if tokens[0].Type == Word && permsRegexp.MatchString(tokens[0].Value) && len(tokens) > 1 {
+		r.PermsFirst = true
+		r.Perms = tokens[0].Value
+		r.Path = tokenText(tokens[1])
+		tokens = tokens[2:]
+	} else {
+		r.Path = tokenText(tokens[0])
+		tokens = tokens[1:]
+
+		if len(tokens) > 0 && tokens[0].Value != "->" {
+			if !permsRegexp.MatchString(tokens[0].Value) {
+				return nil, errorf(tokens[0], "unknown permissions %q of %s", tokens[0].Value, r.Path)
+			}
+
+			r.Perms = tokens[0].Value
+			tokens = tokens[1:]
+		}
+	}
+
+	if len(tokens) > 0 {
+		if tokens[0].Value != "->" || len(tokens) != 2 {
+			return nil, errorf(tokens[0], "unexpected %q in file rule", tokens[0].Value)
+		}
+
+		r.Target = tokenText(tokens[1])
+	}
+
+	if r.Path != "" && r.Perms == "" && !r.Keyword {
+		return nil, &Error{Pos: s.Start, Msg: fmt.Sprintf("missing permissions of %s", r.Path)}
+	}
+
+	return r, nil
+}
+
+func parseChangeProfile(s span, quals Qualifiers, tokens []Token) (Rule, error) {
+	r := &ChangeProfileRule{span: s, Qualifiers: quals}
+	tokens = tokens[1:]
+
+	if len(tokens) > 0 && (tokens[0].Value == "safe" || tokens[0].Value == "unsafe") {
+		r.Mode = tokens[0].Value
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) > 0 && tokens[0].Value != "->" {
+		r.Exec = tokenText(tokens[0])
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) > 0 {
+		if tokens[0].Value != "->" || len(tokens) != 2 {
+			return nil, errorf(tokens[0], "unexpected %q in change_profile rule", tokens[0].Value)
+		}
+
+		r.Target = tokenText(tokens[1])
+	}
+
+	return r, nil
+}
+
+func parseMediationRule(s span, quals Qualifiers, tokens []Token) (Rule, error) {
+	r := &MediationRule{span: s, Qualifiers: quals, Keyword: tokens[0].Value}
+	tokens = tokens[1:]
+
+	for len(tokens) > 0 {
+		t := tokens[0]
+		tokens = tokens[1:]
+
+		switch {
+		case t.Type == LParen:
+			if len(r.Access) > 0 || len(r.Conds) > 0 || len(r.Args) > 0 {
+				return nil, errorf(t, "unexpected '(' in %s rule", r.Keyword)
+			}
+
+			list, rest, err := parseList(t, tokens)
+
+			if err != nil {
+				return nil, err
+			}
+
+			r.Access = list
+			r.AccessList = true
+			tokens = rest
+		case t.Type == Word && t.Value == "->":
+			if len(tokens) != 1 {
+				return nil, errorf(t, "expected a single target after '->'")
+			}
+
+			r.Target = tokenText(tokens[0])
+			tokens = nil
+		case t.Type == Word && strings.Contains(t.Value, "=") && !isPath(t.Value):
+			i := strings.Index(t.Value, "=")
+			c := Conditional{Key: t.Value[:i]}
+
+			if value := t.Value[i+1:]; value != "" {
+				c.Values = []string{value}
+			} else if len(tokens) > 0 && tokens[0].Type == LParen {
+				list, rest, err := parseList(tokens[0], tokens[1:])
+
+				if err != nil {
+					return nil, err
+				}
+
+				c.Values = list
+				c.List = true
+				tokens = rest
+			} else if len(tokens) > 0 && (tokens[0].Type == Word || tokens[0].Type == String) {
+				c.Values = []string{tokenText(tokens[0])}
+				tokens = tokens[1:]
+			} else {
+				return nil, errorf(t, "missing value of %s", c.Key)
+			}
+
+			r.Conds = append(r.Conds, c)
+		case t.Type == Word || t.Type == String:
+			if t.Type == Word && !isPath(t.Value) && len(r.Conds) == 0 && len(r.Args) == 0 && !r.AccessList {
+				r.Access = append(r.Access, t.Value)
+			} else {
+				r.Args = append(r.Args, tokenText(t))
+			}
+		default:
+			return nil, errorf(t, "unexpected %s in %s rule", t.Type, r.Keyword)
+		}
+	}
+
+	return r, nil
+}
+
+// parseList parses the words of a parenthesized list separated by commas or spaces
+func parseList(open Token, tokens []Token) ([]string, []Token, error) {
+	list := []string{}
+
+	for i, t := range tokens {
+		switch t.Type {
+		case RParen:
+			return list, tokens[i+1:], nil
+		case Comma:
+		case Word, String:
+			list = append(list, tokenText(t))
+		default:
+			return nil, nil, errorf(t, "unexpected %s in a list", t.Type)
+		}
+	}
+
+	return nil, nil, errorf(open, "missing ')'")
+}
@@ -0,0 +1,154 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		rules []string
+		err   string
+	}{
+		{
+			name:  "rules without commas at the end of the lines",
+			src:   "/etc/** r,\ncapability net_raw\nnetwork inet stream,",
+			rules: []string{"/etc/** r", "capability net_raw", "network inet stream"},
+		},
+		{
+			name:  "rule without a comma at the end of the input",
+			src:   "/etc/** r",
+			rules: []string{"/etc/** r"},
+		},
+		{
+			name:  "owner and exec target",
+			src:   "owner /tmp/** rw -> foo,",
+			rules: []string{"owner /tmp/** rw -> foo"},
+		},
+		{
+			name:  "qualifiers in the canonical order",
+			src:   "deny audit /proc/** w,",
+			rules: []string{"audit deny /proc/** w"},
+		},
+		{
+			name:  "combined exec mode",
+			src:   "/bin/sh Pix,",
+			rules: []string{"/bin/sh Pix"},
+		},
+		{
+			name:  "network type without a family",
+			src:   "network raw,",
+			rules: []string{"network raw"},
+		},
+		{
+			name: "unknown permissions",
+			src:  "/tmp/** rz,",
+			err:  `line 1:9: unknown permissions "rz" of /tmp/**`,
+		},
+		{
+			name: "unbalanced brace",
+			src:  "/etc/** r, }",
+			err:  "line 1:12: unexpected '}'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := ParseRules(tt.src)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := []string{}
+
+			for _, n := range nodes {
+				r, ok := n.(Rule)
+
+				if !ok {
+					t.Fatalf("expected a rule, got %T", n)
+				}
+
+				got = append(got, r.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.rules) {
+				t.Errorf("expected rules %q, got %q", tt.rules, got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		profiles []string
+		format   string
+		err      string
+	}{
+		{
+			name:     "profile with flags and a hat",
+			src:      "include <tunables/global>\nprofile foo flags=(attach_disconnected) {\n  /etc/** r,\n  ^hat {\n    /tmp/** rw,\n  }\n}\n",
+			profiles: []string{"foo"},
+			format:   "include <tunables/global>\nprofile foo flags=(attach_disconnected) {\n\t/etc/** r,\n\t^hat {\n\t\t/tmp/** rw,\n\t}\n}\n",
+		},
+		{
+			name:     "variable assignment",
+			src:      "@{VAR} = /a /b\nprofile foo {\n}",
+			profiles: []string{"foo"},
+			format:   "@{VAR} = /a /b\nprofile foo {\n}\n",
+		},
+		{
+			name:     "abi and a profile named by its path",
+			src:      "abi <abi/3.0>,\n/usr/bin/app {\n}\n",
+			profiles: []string{"/usr/bin/app"},
+			format:   "abi <abi/3.0>,\n/usr/bin/app {\n}\n",
+		},
+		{
+			name: "missing closing brace",
+			src:  "profile foo {\n  /etc/** r,\n",
+			err:  "line 3:1: missing '}'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pol, err := Parse(tt.src)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names := []string{}
+
+			for _, p := range pol.Profiles() {
+				names = append(names, p.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.profiles) {
+				t.Errorf("expected profiles %q, got %q", tt.profiles, names)
+			}
+
+			if got := Format(pol.Nodes, 0); got != tt.format {
+				t.Errorf("expected format %q, got %q", tt.format, got)
+			}
+		})
+	}
+}
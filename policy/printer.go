@@ -0,0 +1,260 @@
+package policy
+
+import (
+	"strings"
+)
+
+// String renders the policy in the canonical format: one statement per line, blocks indented with tabs,
+// comments and single blank lines between statements are kept
+func (p *Policy) String() string {
+	return Format(p.Nodes, 0)
+}
+
+// Format renders nodes indented by depth tabs, each line ends with a newline
+func Format(nodes []Node, depth int) string {
+	pr := &printer{}
+	pr.nodes(nodes, depth)
+
+	if len(pr.lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(pr.lines, "\n") + "\n"
+}
+
+type printer struct {
+	lines []string
+}
+
+func (pr *printer) line(depth int, text string) {
+	pr.lines = append(pr.lines, strings.Repeat("\t", depth)+text)
+}
+
+func (pr *printer) nodes(nodes []Node, depth int) {
+	var prev Node
+
+	for _, n := range nodes {
+		if c, ok := n.(*CommentNode); ok && c.Trailing && len(pr.lines) > 0 {
+			pr.lines[len(pr.lines)-1] += " #" + c.Text
+			prev = n
+			continue
+		}
+
+		if prev != nil && blankLine(prev, n) {
+			pr.lines = append(pr.lines, "")
+		}
+
+		pr.node(n, depth)
+		prev = n
+	}
+}
+
+// blankLine checks whether a blank line separates two nodes, as in the source or before a generated profile
+func blankLine(prev, n Node) bool {
+	if prev.End().Line > 0 && n.Pos().Line > 0 {
+		return n.Pos().Line > prev.End().Line+1
+	}
+
+	_, profile := n.(*Profile)
+	_, comment := prev.(*CommentNode)
+
+	return profile && !comment
+}
+
+func (pr *printer) node(n Node, depth int) {
+	switch n := n.(type) {
+	case *CommentNode:
+		pr.line(depth, "#"+n.Text)
+	case *ABI:
+		pr.line(depth, "abi "+n.Path.String()+",")
+	case *Include:
+		pr.line(depth, n.String())
+	case *Variable:
+		pr.line(depth, n.String())
+	case *Alias:
+		pr.line(depth, "alias "+n.From+" -> "+n.To+",")
+	case *Profile:
+		pr.line(depth, n.Header()+" {")
+		pr.nodes(n.Body, depth+1)
+		pr.line(depth, "}")
+	case Rule:
+		pr.line(depth, n.String()+",")
+	}
+}
+
+func (p Path) String() string {
+	if p.Magic {
+		return "<" + p.Value + ">"
+	}
+
+	return `"` + p.Value + `"`
+}
+
+func (i *Include) String() string {
+	ret := "include "
+
+	if i.Legacy {
+		ret = "#include "
+	}
+
+	if i.IfExists {
+		ret += "if exists "
+	}
+
+	return ret + i.Path.String()
+}
+
+func (v *Variable) String() string {
+	op := " = "
+
+	if v.Append {
+		op = " += "
+	}
+
+	return v.Name + op + strings.Join(v.Values, " ")
+}
+
+// Header returns the profile declaration without the opening brace
+func (p *Profile) Header() string {
+	ret := p.Qualifiers.String()
+
+	switch {
+	case p.Hat && p.Keyword == "":
+		ret += "^" + p.Name
+	case p.Keyword != "":
+		ret += p.Keyword + " " + quote(p.Name)
+
+		if p.Attachment != "" {
+			ret += " " + p.Attachment
+		}
+	default:
+		ret += quote(p.Name)
+	}
+
+	if len(p.Flags) > 0 {
+		ret += " flags=(" + strings.Join(p.Flags, ",") + ")"
+	}
+
+	return ret
+}
+
+// String returns the qualifiers followed by a space, empty if there is none
+func (q Qualifiers) String() string {
+	ret := ""
+
+	if q.Priority != "" {
+		ret += "priority=" + q.Priority + " "
+	}
+
+	if q.Audit {
+		ret += "audit "
+	}
+
+	if q.Quiet {
+		ret += "quiet "
+	}
+
+	if q.Allow {
+		ret += "allow "
+	}
+
+	if q.Deny {
+		ret += "deny "
+	}
+
+	if q.Owner {
+		ret += "owner "
+	}
+
+	return ret
+}
+
+func (r *FileRule) String() string {
+	parts := []string{}
+
+	if r.Keyword {
+		parts = append(parts, "file")
+	}
+
+	if r.PermsFirst {
+		parts = append(parts, r.Perms, r.Path)
+	} else {
+		parts = append(parts, r.Path, r.Perms)
+	}
+
+	if r.Target != "" {
+		parts = append(parts, "->", r.Target)
+	}
+
+	return r.Qualifiers.String() + join(parts)
+}
+
+func (r *CapabilityRule) String() string {
+	return r.Qualifiers.String() + join(append([]string{"capability"}, r.Capabilities...))
+}
+
+func (r *NetworkRule) String() string {
+	return r.Qualifiers.String() + join(append([]string{"network"}, r.Args...))
+}
+
+func (r *ChangeProfileRule) String() string {
+	parts := []string{"change_profile", r.Mode, r.Exec}
+
+	if r.Target != "" {
+		parts = append(parts, "->", r.Target)
+	}
+
+	return r.Qualifiers.String() + join(parts)
+}
+
+func (r *MediationRule) String() string {
+	parts := []string{r.Keyword}
+
+	if r.AccessList {
+		parts = append(parts, "("+strings.Join(r.Access, ", ")+")")
+	} else {
+		parts = append(parts, r.Access...)
+	}
+
+	for _, c := range r.Conds {
+		parts = append(parts, c.String())
+	}
+
+	parts = append(parts, r.Args...)
+
+	if r.Target != "" {
+		parts = append(parts, "->", r.Target)
+	}
+
+	return r.Qualifiers.String() + join(parts)
+}
+
+func (c Conditional) String() string {
+	if c.List {
+		return c.Key + "=(" + strings.Join(c.Values, ", ") + ")"
+	}
+
+	return c.Key + "=" + strings.Join(c.Values, " ")
+}
+
+// join joins the non empty parts with spaces
+func join(parts []string) string {
+	nonEmpty := []string{}
+
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+
+	return strings.Join(nonEmpty, " ")
+}
+
+// quote quotes a profile name containing spaces
+func quote(name string) string {
+	if strings.ContainsAny(name, " \t") {
+		return `"` + name + `"`
+	}
+
+	return name
+}
@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/policy"
 )
 
 const (
 	enforced = "enforce"
+
+	// FragmentIncludeDir is the directory of the fragments, relative to the AppArmor policy directory
+	FragmentIncludeDir = "abstractions/kube-apparmor-manager"
 )
 
 type AppArmorProfileStatus struct {
@@ -61,31 +67,116 @@ type AppArmorProfile struct {
 
 		  	/usr/sbin/tcpdump r,
 	*/
-	Enforced bool
+	Mode v1beta1.ProfileMode
+	// Flags of the profile, no flags are set if empty
+	Flags []string
+	// Attachment is the executable path glob the profile is attached to, none if empty
+	Attachment string
+	// ABI and Includes make the header of the profile
+	ABI      string
+	Includes []string
+	// Raw is a complete profile text deployed verbatim instead of the rules
+	Raw string
+	// Structured are the rules rendered before the rules
+	Structured []policy.Rule
+	// Fragments are the names of the included fragments
+	Fragments []string
+	// Deleted tells that the object is being deleted, the profile is removed from the nodes instead of deployed
+	Deleted bool
 }
 
-func (p AppArmorProfile) String() string {
-	ret := ""
+// AppArmorProfileFragment is a set of rules deployed as an abstraction and included by profiles
+type AppArmorProfileFragment struct {
+	Name  string
+	Rules string
+	// Structured are the rules rendered before the rules
+	Structured []policy.Rule
+}
+
+// FragmentInclude returns the path a fragment is included with
+func FragmentInclude(name string) string {
+	return FragmentIncludeDir + "/" + name
+}
 
-	ret += fmt.Sprintf("profile %s flags=(attach_disconnected,mediate_deleted) {\n", p.Name)
+// String renders the rules of the fragment
+func (f AppArmorProfileFragment) String() string {
+	return strings.TrimRight(renderBody(f.Structured, nil, f.Rules, 0), "\n")
+}
 
-	lines := strings.Split(p.Rules, "\n")
+// flags returns the profile flags, the kill and unconfined modes are set in the profile itself
+func (p AppArmorProfile) flags() []string {
+	flags := append([]string{}, p.Flags...)
 
-	for _, line := range lines {
-		// skip empty line and commented out rule
-		if line == "" || line[0] == '#' {
-			continue
+	switch p.Mode {
+	case v1beta1.ModeKill, v1beta1.ModeUnconfined:
+		for _, f := range flags {
+			if f == string(p.Mode) {
+				return flags
+			}
 		}
 
-		last := line[len(line)-1:]
+		flags = append(flags, string(p.Mode))
+	}
 
-		// append ',' at the end if it doesn't exists
-		if last != "," {
-			line += ","
-		}
-		ret += fmt.Sprintf("\t%s\n", line)
+	return flags
+}
+
+// String renders the profile, the rules are parsed so that comments, blocks and rules spanning several
+// lines are kept intact, and a missing comma at the end of a rule line is added
+func (p AppArmorProfile) String() string {
+	if p.Raw != "" {
+		return strings.TrimRight(p.Raw, "\n")
+	}
+
+	nodes := []policy.Node{}
+
+	if p.ABI != "" {
+		nodes = append(nodes, &policy.ABI{Path: policy.Path{Value: p.ABI, Magic: true}})
+	}
+
+	for _, include := range p.Includes {
+		nodes = append(nodes, &policy.Include{Path: policy.Path{Value: include, Magic: true}})
+	}
+
+	profile := &policy.Profile{
+		Keyword:    "profile",
+		Name:       p.Name,
+		Attachment: p.Attachment,
+		Flags:      p.flags(),
+	}
+
+	nodes = append(nodes, profile)
+
+	ret := policy.Format(nodes, 0)
+
+	// the body is rendered in place of the closing brace
+	return strings.TrimSuffix(ret, "}\n") + renderBody(p.Structured, p.Fragments, p.Rules, 1) + "}"
+}
+
+// renderBody renders the fragment includes, the structured rules and the rules, the rules which don't
+// parse are refused by the validation and rendered as is for apparmor_parser to report the error
+func renderBody(structured []policy.Rule, fragments []string, rules string, depth int) string {
+	body := []policy.Node{}
+
+	for _, f := range fragments {
+		body = append(body, &policy.Include{Path: policy.Path{Value: FragmentInclude(f), Magic: true}})
+	}
+
+	for _, r := range structured {
+		body = append(body, r)
+	}
+
+	parsed, err := policy.ParseRules(rules)
+
+	if err == nil {
+		return policy.Format(append(body, parsed...), depth)
+	}
+
+	ret := policy.Format(body, depth)
+
+	for _, line := range strings.Split(strings.TrimRight(rules, "\n"), "\n") {
+		ret += fmt.Sprintf("%s%s\n", strings.Repeat("\t", depth), strings.TrimSpace(line))
 	}
 
-	ret += "}"
 	return ret
 }
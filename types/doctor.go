@@ -0,0 +1,54 @@
+package types
+
+import (
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Finding statuses
+const (
+	FindingOK      = "ok"
+	FindingWarning = "warning"
+	FindingError   = "error"
+	// FindingUnverified tells that the check couldn't tell whether it passes
+	FindingUnverified = "unverified"
+)
+
+// Finding is the result of a readiness check on a node
+type Finding struct {
+	Node    string
+	Check   string
+	Status  string
+	Message string
+	// Fix suggests how to fix a failed check
+	Fix string
+}
+
+type Findings []Finding
+
+// Failed checks whether any check failed with an error
+func (f Findings) Failed() bool {
+	for _, finding := range f {
+		if finding.Status == FindingError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrintFindings prints the readiness check results
+func (f Findings) PrintFindings() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Node Name", "Check", "Status", "Details", "Suggested Fix"})
+
+	data := [][]string{}
+
+	for _, finding := range f {
+		data = append(data, []string{finding.Node, finding.Check, finding.Status, finding.Message, finding.Fix})
+	}
+
+	table.AppendBulk(data)
+	table.Render()
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
 
@@ -14,20 +15,132 @@ const (
 	Master    = "master"
 )
 
+// Node address types, the values match the Kubernetes node address types
+const (
+	AddressInternalIP  = "InternalIP"
+	AddressExternalIP  = "ExternalIP"
+	AddressInternalDNS = "InternalDNS"
+	AddressExternalDNS = "ExternalDNS"
+	AddressHostname    = "Hostname"
+)
+
+// IP families an IP address type of the preference can be restricted to, e.g. InternalIP/IPv6
+const (
+	FamilyIPv4 = "IPv4"
+	FamilyIPv6 = "IPv6"
+)
+
+// DefaultAddressPreference is the order in which node addresses are tried when connecting to a node
+var DefaultAddressPreference = []string{AddressExternalIP, AddressInternalIP, AddressExternalDNS, AddressInternalDNS, AddressHostname}
+
+// ValidateAddressType checks whether the address type is known
+func ValidateAddressType(addrType string) error {
+	switch addrType {
+	case AddressInternalIP, AddressExternalIP, AddressInternalDNS, AddressExternalDNS, AddressHostname:
+		return nil
+	}
+
+	return fmt.Errorf("unknown address type: %s", addrType)
+}
+
+// ValidateAddressPreference checks whether the address types are known, IP address types may be followed by an
+// IP family, e.g. InternalIP/IPv6
+func ValidateAddressPreference(preference []string) error {
+	for _, p := range preference {
+		addrType, family := splitPreference(p)
+
+		err := ValidateAddressType(addrType)
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case family == "":
+		case addrType != AddressInternalIP && addrType != AddressExternalIP:
+			return fmt.Errorf("address type %s has no IP family: %s", addrType, p)
+		case family != FamilyIPv4 && family != FamilyIPv6:
+			return fmt.Errorf("unknown IP family: %s", p)
+		}
+	}
+
+	return nil
+}
+
+// splitPreference returns the address type and the IP family, if any, of an entry of the address preference
+func splitPreference(p string) (string, string) {
+	parts := strings.SplitN(p, "/", 2)
+
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// ipFamily returns the IP family of an address, empty if it is not an IP address
+func ipFamily(address string) string {
+	ip := net.ParseIP(address)
+
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return FamilyIPv4
+	default:
+		return FamilyIPv6
+	}
+}
+
+// Node sources
+const (
+	SourceKubernetes = "kubernetes"
+	SourceInventory  = "inventory"
+)
+
+// Reasons for skipping a node
+const (
+	SkipNotReady    = "NotReady"
+	SkipCordoned    = "cordoned"
+	SkipNoAddress   = "no usable address"
+	SkipExcluded    = "excluded by filter"
+	SkipUnreachable = "unreachable"
+	SkipUnsupported = "unsupported"
+)
+
 type NodeList []*Node
 
 type Node struct {
-	NodeName        string
-	ExternalIP      string
-	InternalIP      string
+	NodeName   string
+	ExternalIP string
+	InternalIP string
+	// Addresses contains the addresses of each address type in the order they are reported, dual-stack nodes
+	// have one of each IP family
+	Addresses       map[string][]string
 	Role            string
 	AppArmorEnabled bool
 	AppArmorStatus  *AppArmorProfileStatus
+	// SkipReason is set when the node is not operated on
+	SkipReason string
+	// SSH overrides the default SSH settings for this node
+	SSH *SSHConfig
+	// Source tells whether the node is a Kubernetes node or an inventory host
+	Source string
+	// BootID changes every time the node restarts, only set for Kubernetes nodes
+	BootID string
+}
+
+// SSHConfig contains per node SSH settings, empty fields fall back to the defaults
+type SSHConfig struct {
+	User    string `json:"user,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	KeyFile string `json:"keyFile,omitempty"`
 }
 
 // NewNode returns a new node object
 func NewNode() *Node {
 	return &Node{
+		Addresses:      map[string][]string{},
 		AppArmorStatus: NewAppArmorStatus(),
 	}
 }
@@ -35,11 +148,11 @@ func NewNode() *Node {
 func (nl NodeList) String() string {
 	ret := ""
 
-	ret += strings.Join([]string{"Node Name", "Internal IP", "External IP", "Role", "AppArmor Enabled"}, "\t")
+	ret += strings.Join([]string{"Node Name", "Internal IP", "External IP", "Role", "AppArmor Enabled", "Skip Reason"}, "\t")
 	ret += "\n"
 
 	for _, n := range nl {
-		ret += strings.Join([]string{n.NodeName, n.InternalIP, n.ExternalIP, n.Role, fmt.Sprintf("%t", n.AppArmorEnabled)}, "\t")
+		ret += strings.Join([]string{n.NodeName, n.InternalIP, n.ExternalIP, n.Role, fmt.Sprintf("%t", n.AppArmorEnabled), n.SkipReason}, "\t")
 		ret += "\n"
 	}
 
@@ -49,31 +162,110 @@ func (nl NodeList) String() string {
 func (nl NodeList) GetEnforcedProfiles() string {
 	ret := ""
 
-	ret += strings.Join([]string{"Node Name", "Role", "Enforced Profiles"}, "\t")
+	ret += strings.Join([]string{"Node Name", "Role", "Enforced Profiles", "Skip Reason"}, "\t")
 	ret += "\n"
 
 	for _, n := range nl {
-		ret += strings.Join([]string{n.NodeName, n.Role, strings.Join(n.AppArmorStatus.GetEnforcedProfiles(), ",")}, "\t")
+		ret += strings.Join([]string{n.NodeName, n.Role, strings.Join(n.AppArmorStatus.GetEnforcedProfiles(), ","), n.SkipReason}, "\t")
 		ret += "\n"
 	}
 
 	return ret
 }
 
+// IsKubernetes checks whether a node is a Kubernetes node
+func (n *Node) IsKubernetes() bool {
+	return n.Source == SourceKubernetes
+}
+
 // IsMaster checks whether a node is master node
 func (n *Node) IsMaster() bool {
 	return n.Role == Master
 }
 
+// AddAddress adds an address of the given type, the first IP address of each type is the one displayed
+func (n *Node) AddAddress(addrType, address string) {
+	if len(n.Addresses[addrType]) == 0 {
+		switch addrType {
+		case AddressInternalIP:
+			n.InternalIP = address
+		case AddressExternalIP:
+			n.ExternalIP = address
+		}
+	}
+
+	n.Addresses[addrType] = append(n.Addresses[addrType], address)
+}
+
+// Address returns the first address following the address type preference, an address type followed by an IP
+// family only matches the addresses of this family
+func (n *Node) Address(preference []string) string {
+	for _, p := range preference {
+		addrType, family := splitPreference(p)
+
+		for _, addr := range n.Addresses[addrType] {
+			if addr != "" && (family == "" || ipFamily(addr) == family) {
+				return addr
+			}
+		}
+	}
+
+	return ""
+}
+
+// Skip marks the node as skipped with the given reason
+func (n *Node) Skip(reason string) {
+	n.SkipReason = reason
+}
+
+// Skipped checks whether a node is skipped
+func (n *Node) Skipped() bool {
+	return n.SkipReason != ""
+}
+
+// Skipped returns the skipped nodes
+func (nl NodeList) Skipped() NodeList {
+	skipped := NodeList{}
+
+	for _, n := range nl {
+		if n.Skipped() {
+			skipped = append(skipped, n)
+		}
+	}
+
+	return skipped
+}
+
+// PrintSkipped prints the skipped nodes and the reasons, nothing is printed if no node was skipped
+func (nl NodeList) PrintSkipped() {
+	skipped := nl.Skipped()
+
+	if len(skipped) == 0 {
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Skipped Node", "Role", "Skip Reason"})
+
+	data := [][]string{}
+
+	for _, n := range skipped {
+		data = append(data, []string{n.NodeName, n.Role, n.SkipReason})
+	}
+
+	table.AppendBulk(data)
+	table.Render()
+}
+
 // PrintEnforcementStatus prints enforced AppArmor profile on worker nodes
 func (nl NodeList) PrintEnforcementStatus() {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Node Name", "Role", "Enforced Profiles"})
+	table.SetHeader([]string{"Node Name", "Role", "Enforced Profiles", "Skip Reason"})
 
 	data := [][]string{}
 
 	for _, n := range nl {
-		data = append(data, []string{n.NodeName, n.Role, strings.Join(n.AppArmorStatus.GetEnforcedProfiles(), ",")})
+		data = append(data, []string{n.NodeName, n.Role, strings.Join(n.AppArmorStatus.GetEnforcedProfiles(), ","), n.SkipReason})
 	}
 
 	table.AppendBulk(data)
@@ -83,14 +275,52 @@ func (nl NodeList) PrintEnforcementStatus() {
 // PrintEnabledStatus prints AppArmor enabled status on worker nodes
 func (nl NodeList) PrintEnabledStatus() {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Node Name", "Internal IP", "External IP", "Role", "AppArmor Enabled"})
+	table.SetHeader([]string{"Node Name", "Internal IP", "External IP", "Role", "AppArmor Enabled", "Skip Reason"})
 
 	data := [][]string{}
 
 	for _, n := range nl {
-		data = append(data, []string{n.NodeName, n.InternalIP, n.ExternalIP, n.Role, fmt.Sprintf("%t", n.AppArmorEnabled)})
+		data = append(data, []string{n.NodeName, n.InternalIP, n.ExternalIP, n.Role, fmt.Sprintf("%t", n.AppArmorEnabled), n.SkipReason})
 	}
 
 	table.AppendBulk(data)
 	table.Render()
 }
+
+// NodeFilter narrows down the nodes a command operates on
+type NodeFilter struct {
+	// Selector is a Kubernetes label selector, e.g. "pool=frontend"
+	Selector string
+	// Nodes limits the list to the given node names
+	Nodes []string
+	// ExcludeNodes removes the given node names from the list
+	ExcludeNodes []string
+	// IncludeSkipped operates on NotReady and cordoned nodes instead of skipping them
+	IncludeSkipped bool
+}
+
+// Restricts checks whether the filter leaves some nodes out
+func (f NodeFilter) Restricts() bool {
+	return f.Selector != "" || len(f.Nodes) > 0 || len(f.ExcludeNodes) > 0
+}
+
+// Match checks whether a node name passes the name filters
+func (f NodeFilter) Match(name string) bool {
+	for _, n := range f.ExcludeNodes {
+		if n == name {
+			return false
+		}
+	}
+
+	if len(f.Nodes) == 0 {
+		return true
+	}
+
+	for _, n := range f.Nodes {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"strings"
+)
+
+// OSRelease contains the /etc/os-release fields used to identify the distribution of a node
+type OSRelease struct {
+	ID         string
+	IDLike     []string
+	VersionID  string
+	VariantID  string
+	PrettyName string
+}
+
+// ParseOSRelease parses the content of /etc/os-release
+func ParseOSRelease(content string) OSRelease {
+	osr := OSRelease{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		value := strings.Trim(kv[1], `"'`)
+
+		switch kv[0] {
+		case "ID":
+			osr.ID = strings.ToLower(value)
+		case "ID_LIKE":
+			osr.IDLike = strings.Fields(strings.ToLower(value))
+		case "VERSION_ID":
+			osr.VersionID = value
+		case "VARIANT_ID":
+			osr.VariantID = strings.ToLower(value)
+		case "PRETTY_NAME":
+			osr.PrettyName = value
+		}
+	}
+
+	return osr
+}
+
+// Is checks whether the distribution is one of the given ids or derives from one of them
+func (o OSRelease) Is(ids ...string) bool {
+	for _, id := range ids {
+		if o.ID == id {
+			return true
+		}
+
+		for _, like := range o.IDLike {
+			if like == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (o OSRelease) String() string {
+	if o.PrettyName != "" {
+		return o.PrettyName
+	}
+
+	return strings.TrimSpace(o.ID + " " + o.VersionID)
+}
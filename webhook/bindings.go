@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	admission "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/crd"
+	"github.com/sysdiglabs/kube-apparmor-manager/workload"
+)
+
+const (
+	// InjectAnnotation assigns the profiles with the container.apparmor.security.beta.kubernetes.io annotations
+	InjectAnnotation = "annotation"
+	// InjectField assigns the profiles with the securityContext.appArmorProfile field of the containers, Kubernetes 1.30+
+	InjectField = "field"
+)
+
+// BindingSource returns the AppArmorProfileBinding objects and the namespaces of the cluster
+type BindingSource interface {
+	ListAppArmorProfileBindings() ([]v1beta1.AppArmorProfileBinding, error)
+	GetNamespace(name string) (*corev1.Namespace, error)
+}
+
+type binder struct {
+	source BindingSource
+	inject string
+}
+
+// patchOperation is a JSON patch operation
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// EnableProfileBinding serves the webhook assigning the profiles of the AppArmorProfileBinding objects to the
+// containers of new pods, with the annotations or the securityContext.appArmorProfile field
+func (s *Server) EnableProfileBinding(source BindingSource, inject string) error {
+	if inject != InjectAnnotation && inject != InjectField {
+		return fmt.Errorf("unknown profile injection: %s", inject)
+	}
+
+	b := &binder{source: source, inject: inject}
+
+	s.mux.HandleFunc(crd.MutationPath, serveReview(b.review))
+
+	return nil
+}
+
+// review handles an AdmissionReview of a pod
+func (b *binder) review(body []byte) (interface{}, error) {
+	review := admission.AdmissionReview{}
+
+	err := json.Unmarshal(body, &review)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("missing admission request")
+	}
+
+	pod, err := workload.ParsePod(review.Request.Object.Raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &admission.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+
+	patch, err := b.patch(review.Request.Namespace, pod)
+
+	if err != nil {
+		// the pods are admitted unchanged when the cluster can't be read
+		klog.Warningf("Failed to assign AppArmor profiles to pod %s/%s: %v", review.Request.Namespace, pod.Name(), err)
+	}
+
+	if err == nil && len(patch) > 0 {
+		response.Patch, err = json.Marshal(patch)
+
+		if err != nil {
+			return nil, err
+		}
+
+		patchType := admission.PatchTypeJSONPatch
+		response.PatchType = &patchType
+	}
+
+	review.Request = nil
+	review.Response = response
+
+	return review, nil
+}
+
+// patch returns the operations assigning profiles to the containers of a pod, the containers which already
+// have a profile are left unchanged
+func (b *binder) patch(namespace string, pod *workload.Pod) ([]patchOperation, error) {
+	bindings, err := b.matchingBindings(namespace, pod)
+
+	if err != nil || len(bindings) == 0 {
+		return nil, err
+	}
+
+	patch := []patchOperation{}
+	annotated := false
+
+	for _, list := range []struct {
+		field      string
+		containers []workload.Container
+	}{
+		{"initContainers", pod.Spec.InitContainers},
+		{"containers", pod.Spec.Containers},
+	} {
+		for i, c := range list.containers {
+			if pod.HasProfile(c) {
+				continue
+			}
+
+			profile, binding := boundProfile(bindings, c.Name)
+
+			if profile == "" {
+				continue
+			}
+
+			klog.Infof("Assigning profile %s to container %s of pod %s/%s, bound by %s", profile, c.Name, namespace, pod.Name(), binding)
+
+			if b.inject == InjectField {
+				field := workload.Profile{Type: workload.ProfileTypeLocalhost, LocalhostProfile: profile}
+				containerPath := fmt.Sprintf("/spec/%s/%d/securityContext", list.field, i)
+
+				if c.SecurityContext == nil {
+					patch = append(patch, patchOperation{Op: "add", Path: containerPath, Value: map[string]interface{}{"appArmorProfile": field}})
+				} else {
+					patch = append(patch, patchOperation{Op: "add", Path: containerPath + "/appArmorProfile", Value: field})
+				}
+
+				continue
+			}
+
+			if !annotated && pod.Metadata.Annotations == nil {
+				patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations", Value: map[string]string{}})
+			}
+
+			annotated = true
+			key := workload.AnnotationPrefix + c.Name
+			value := workload.Profile{Type: workload.ProfileTypeLocalhost, LocalhostProfile: profile}.Annotation()
+			patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" + escapePointer(key), Value: value})
+		}
+	}
+
+	return patch, nil
+}
+
+// matchingBindings returns the bindings selecting a pod, sorted by name
+func (b *binder) matchingBindings(namespace string, pod *workload.Pod) ([]v1beta1.AppArmorProfileBinding, error) {
+	bindings, err := b.source.ListAppArmorProfileBindings()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+
+	var namespaceLabels labels.Set
+	matching := []v1beta1.AppArmorProfileBinding{}
+
+	for _, binding := range bindings {
+		if !matchesSelector(binding.Spec.PodSelector, labels.Set(pod.Metadata.Labels)) {
+			continue
+		}
+
+		if binding.Spec.NamespaceSelector != nil && namespaceLabels == nil {
+			ns, err := b.source.GetNamespace(namespace)
+
+			if err != nil {
+				return nil, err
+			}
+
+			namespaceLabels = labels.Set(ns.Labels)
+
+			if namespaceLabels == nil {
+				namespaceLabels = labels.Set{}
+			}
+		}
+
+		if !matchesSelector(binding.Spec.NamespaceSelector, namespaceLabels) {
+			continue
+		}
+
+		matching = append(matching, binding)
+	}
+
+	return matching, nil
+}
+
+// matchesSelector checks whether labels match a selector, a missing selector matches everything
+func matchesSelector(selector *metav1.LabelSelector, set labels.Set) bool {
+	if selector == nil {
+		return true
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+
+	if err != nil {
+		klog.Warningf("Ignoring invalid selector: %v", err)
+		return false
+	}
+
+	return s.Matches(set)
+}
+
+// boundProfile returns the profile of the first container entry matching a container name and the binding it belongs to
+func boundProfile(bindings []v1beta1.AppArmorProfileBinding, container string) (string, string) {
+	for _, binding := range bindings {
+		for _, c := range binding.Spec.Containers {
+			matched, err := path.Match(c.Name, container)
+
+			if c.Name == "" || (err == nil && matched) {
+				return c.Profile, binding.Name
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// escapePointer escapes a JSON pointer reference token
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+)
+
+// convert handles a ConversionReview, the objects are converted through v1beta1
+func convert(body []byte) (interface{}, error) {
+	review := apiextensions.ConversionReview{}
+
+	err := json.Unmarshal(body, &review)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("missing conversion request")
+	}
+
+	response := &apiextensions.ConversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+
+	for _, obj := range review.Request.Objects {
+		converted, err := convertObject(obj.Raw, review.Request.DesiredAPIVersion)
+
+		if err != nil {
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+			}
+			break
+		}
+
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	review.Request = nil
+	review.Response = response
+
+	return review, nil
+}
+
+// convertObject converts an AppArmorProfile to the desired API version
+func convertObject(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	meta := metav1.TypeMeta{}
+
+	err := json.Unmarshal(raw, &meta)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.Kind != v1alpha1.Kind {
+		return nil, fmt.Errorf("unsupported kind: %s", meta.Kind)
+	}
+
+	if meta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	hub := &v1beta1.AppArmorProfile{}
+
+	switch meta.APIVersion {
+	case v1beta1.SchemeGroupVersion.String():
+		err = json.Unmarshal(raw, hub)
+	case v1alpha1.SchemeGroupVersion.String():
+		in := &v1alpha1.AppArmorProfile{}
+
+		err = json.Unmarshal(raw, in)
+
+		if err == nil {
+			err = v1beta1.ConvertFromV1alpha1(in, hub)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion: %s", meta.APIVersion)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch desiredAPIVersion {
+	case v1beta1.SchemeGroupVersion.String():
+		hub.TypeMeta = metav1.TypeMeta{APIVersion: desiredAPIVersion, Kind: v1alpha1.Kind}
+		return json.Marshal(hub)
+	case v1alpha1.SchemeGroupVersion.String():
+		out := &v1alpha1.AppArmorProfile{}
+
+		err = v1beta1.ConvertToV1alpha1(hub, out)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(out)
+	}
+
+	return nil, fmt.Errorf("unsupported desired apiVersion: %s", desiredAPIVersion)
+}
@@ -0,0 +1,339 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admission "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+	"github.com/sysdiglabs/kube-apparmor-manager/crd"
+	"github.com/sysdiglabs/kube-apparmor-manager/workload"
+)
+
+const (
+	// PodCheckWarn admits the pods using profiles which are not loaded and returns a warning
+	PodCheckWarn = "warn"
+	// PodCheckDeny refuses the pods using profiles which are not loaded
+	PodCheckDeny = "deny"
+
+	// maxListedNodes limits the nodes listed in a message
+	maxListedNodes = 5
+)
+
+// ProfileSource returns the AppArmorProfile objects and the nodes of the cluster
+type ProfileSource interface {
+	GetAppArmorProfileObject(name string) (*v1beta1.AppArmorProfile, error)
+	ListNodes() ([]corev1.Node, error)
+}
+
+type podChecker struct {
+	source ProfileSource
+	mode   string
+}
+
+// podReview is an AdmissionReview with warnings, which the API types of this Kubernetes version don't have
+type podReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Response        *podResponse `json:"response"`
+}
+
+type podResponse struct {
+	*admission.AdmissionResponse
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// EnablePodCheck serves the webhook checking that the localhost profiles of the pods, set with the annotations or the
+// securityContext.appArmorProfile fields, are AppArmorProfile objects
+// loaded on the nodes the pods can be scheduled to, the mode tells whether the pods are refused or warned about
+func (s *Server) EnablePodCheck(source ProfileSource, mode string) error {
+	if mode != PodCheckWarn && mode != PodCheckDeny {
+		return fmt.Errorf("unknown pod check mode: %s", mode)
+	}
+
+	c := &podChecker{source: source, mode: mode}
+
+	s.mux.HandleFunc(crd.PodValidationPath, serveReview(c.review))
+
+	return nil
+}
+
+// review handles an AdmissionReview of a pod
+func (c *podChecker) review(body []byte) (interface{}, error) {
+	review := admission.AdmissionReview{}
+
+	err := json.Unmarshal(body, &review)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("missing admission request")
+	}
+
+	pod := &corev1.Pod{}
+
+	err = json.Unmarshal(review.Request.Object.Raw, pod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := workload.ParsePod(review.Request.Object.Raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &podResponse{
+		AdmissionResponse: &admission.AdmissionResponse{
+			UID:     review.Request.UID,
+			Allowed: true,
+		},
+	}
+
+	problems, err := c.check(pod, fields.LocalhostProfiles())
+
+	switch {
+	case err != nil:
+		// the pods are not blocked when the cluster can't be read
+		klog.Warningf("Failed to check the AppArmor profiles of pod %s/%s: %v", review.Request.Namespace, fields.Name(), err)
+	case len(problems) > 0 && c.mode == PodCheckDeny:
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("pod %s uses AppArmor profiles which are not loaded: %s", fields.Name(), strings.Join(problems, "; ")),
+		}
+	case len(problems) > 0:
+		response.Warnings = problems
+	}
+
+	return podReview{TypeMeta: review.TypeMeta, Response: response}, nil
+}
+
+// check returns the problems of the localhost profiles the containers of a pod use, indexed by profile
+func (c *podChecker) check(pod *corev1.Pod, profiles map[string][]string) ([]string, error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+
+	nodes, err := c.source.ListNodes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := schedulableNodes(pod, nodes)
+	problems := []string{}
+
+	for _, name := range sortedKeys(profiles) {
+		sort.Strings(profiles[name])
+		containers := strings.Join(profiles[name], ", ")
+
+		p, err := c.source.GetAppArmorProfileObject(name)
+
+		if apierrors.IsNotFound(err) {
+			problems = append(problems, fmt.Sprintf("profile %s of container %s is not an AppArmorProfile object", name, containers))
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if p.DeletionTimestamp != nil {
+			problems = append(problems, fmt.Sprintf("profile %s of container %s is being deleted", name, containers))
+			continue
+		}
+
+		if p.Spec.GetMode() == v1beta1.ModeDisable {
+			problems = append(problems, fmt.Sprintf("profile %s of container %s is disabled", name, containers))
+			continue
+		}
+
+		loaded := map[string]bool{}
+
+		for _, node := range p.Status.Nodes {
+			loaded[node] = true
+		}
+
+		missing := []string{}
+
+		for _, node := range candidates {
+			if !loaded[node] {
+				missing = append(missing, node)
+			}
+		}
+
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("profile %s of container %s is not loaded on nodes the pod can be scheduled to: %s",
+				name, containers, listNodes(missing)))
+		}
+	}
+
+	return problems, nil
+}
+
+// schedulableNodes returns the names of the nodes a pod can be scheduled to according to its node name,
+// node selector, required node affinity and tolerations
+func schedulableNodes(pod *corev1.Pod, nodes []corev1.Node) []string {
+	names := []string{}
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if pod.Spec.NodeName != "" {
+			if node.Name == pod.Spec.NodeName {
+				names = append(names, node.Name)
+			}
+			continue
+		}
+
+		if node.Spec.Unschedulable || !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+			continue
+		}
+
+		if !matchesNodeAffinity(pod, node) || !toleratesTaints(pod, node) {
+			continue
+		}
+
+		names = append(names, node.Name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// matchesNodeAffinity checks whether a node matches one of the required node selector terms of a pod
+func matchesNodeAffinity(pod *corev1.Pod, node *corev1.Node) bool {
+	affinity := pod.Spec.Affinity
+
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesNodeSelectorTerm(term, node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// matchesNodeSelectorTerm checks whether a node matches all the requirements of a term, an empty term matches no node
+func matchesNodeSelectorTerm(term corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+
+	for _, expr := range term.MatchExpressions {
+		op, ok := nodeSelectorOperators[expr.Operator]
+
+		if !ok {
+			return false
+		}
+
+		req, err := labels.NewRequirement(expr.Key, op, expr.Values)
+
+		if err != nil || !req.Matches(labels.Set(node.Labels)) {
+			return false
+		}
+	}
+
+	for _, field := range term.MatchFields {
+		if field.Key != "metadata.name" {
+			return false
+		}
+
+		found := false
+
+		for _, value := range field.Values {
+			found = found || value == node.Name
+		}
+
+		switch field.Operator {
+		case corev1.NodeSelectorOpIn:
+			if !found {
+				return false
+			}
+		case corev1.NodeSelectorOpNotIn:
+			if found {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// toleratesTaints checks whether a pod tolerates the taints keeping pods off a node
+func toleratesTaints(pod *corev1.Pod, node *corev1.Node) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listNodes returns the first node names and how many more there are
+func listNodes(names []string) string {
+	if len(names) <= maxListedNodes {
+		return strings.Join(names, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedNodes], ", "), len(names)-maxListedNodes)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := []string{}
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,85 @@
+// Package webhook serves the webhooks the Kubernetes API server calls for the AppArmorProfile CRD and pods
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"k8s.io/klog"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/crd"
+)
+
+const (
+	// maxRequestSize limits the size of the reviews sent by the API server
+	maxRequestSize = 3 * 1024 * 1024
+)
+
+// Server serves the webhooks over TLS
+type Server struct {
+	mux       *http.ServeMux
+	validator *validator
+}
+
+// NewServer returns a new webhook server
+func NewServer() *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		validator: &validator{},
+	}
+
+	s.mux.HandleFunc(crd.ConversionPath, serveReview(convert))
+	s.mux.HandleFunc(crd.ValidationPath, serveReview(s.validator.review))
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return s
+}
+
+// Run serves the webhooks on the address until it fails
+func (s *Server) Run(addr, certFile, keyFile string) error {
+	klog.Infof("Serving webhooks on %s", addr)
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: s.mux,
+	}
+
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
+
+// serveReview decodes the review sent by the API server, passes it to the handler and encodes the response
+func serveReview(handler func(body []byte) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("unsupported method: %s", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := handler(body)
+
+		if err != nil {
+			klog.Errorf("Invalid request to %s: %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		err = json.NewEncoder(w).Encode(response)
+
+		if err != nil {
+			klog.Errorf("Failed to write the response to %s: %v", r.URL.Path, err)
+		}
+	}
+}
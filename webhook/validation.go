@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1alpha1"
+	"github.com/sysdiglabs/kube-apparmor-manager/api/types/v1beta1"
+)
+
+// FragmentUserSource returns the AppArmorProfile objects of the cluster, which may include fragments
+type FragmentUserSource interface {
+	ListAppArmorProfileObjects() ([]v1beta1.AppArmorProfile, error)
+}
+
+type validator struct {
+	// source is set once the deletion of the fragments included by profiles is refused
+	source FragmentUserSource
+}
+
+// EnableFragmentCheck refuses the deletion of the AppArmorProfileFragment objects still included by AppArmorProfile
+// objects, their profiles would include a missing file on the nodes
+func (s *Server) EnableFragmentCheck(source FragmentUserSource) {
+	s.validator.source = source
+}
+
+// review handles an AdmissionReview, invalid AppArmorProfile, AppArmorProfileFragment and AppArmorProfileBinding
+// objects are refused with the error the sync or the mutating webhook would fail with
+func (v *validator) review(body []byte) (interface{}, error) {
+	review := admission.AdmissionReview{}
+
+	err := json.Unmarshal(body, &review)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("missing admission request")
+	}
+
+	response := &admission.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+
+	if review.Request.Operation == admission.Delete {
+		err = v.validateDeletion(review.Request)
+
+		if err != nil {
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonForbidden,
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("%s %s can't be deleted: %v", review.Request.Kind.Kind, review.Request.Name, err),
+			}
+		}
+	} else {
+		err = validateObject(review.Request.Object.Raw)
+
+		if err != nil {
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonInvalid,
+				Code:    http.StatusUnprocessableEntity,
+				Message: fmt.Sprintf("%s %s is invalid: %v", review.Request.Kind.Kind, review.Request.Name, err),
+			}
+		}
+	}
+
+	review.Request = nil
+	review.Response = response
+
+	return review, nil
+}
+
+// validateDeletion refuses the deletion of a fragment which profiles include, the other objects are deleted freely
+func (v *validator) validateDeletion(request *admission.AdmissionRequest) error {
+	if request.Kind.Kind != v1beta1.FragmentKind || v.source == nil {
+		return nil
+	}
+
+	profiles, err := v.source.ListAppArmorProfileObjects()
+
+	if err != nil {
+		return fmt.Errorf("failed to list the profiles including it: %v", err)
+	}
+
+	users := []string{}
+
+	for _, p := range profiles {
+		for _, fragment := range p.Spec.Fragments {
+			if string(fragment) == request.Name {
+				users = append(users, p.Name)
+				break
+			}
+		}
+	}
+
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("it is included by AppArmorProfile %s, remove it from their fragments first", strings.Join(users, ", "))
+	}
+
+	return nil
+}
+
+// validateObject validates an AppArmorProfile of any version, an AppArmorProfileFragment or an AppArmorProfileBinding
+func validateObject(raw []byte) error {
+	meta := metav1.TypeMeta{}
+
+	err := json.Unmarshal(raw, &meta)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case meta.Kind == v1beta1.FragmentKind && meta.APIVersion == v1beta1.SchemeGroupVersion.String():
+		f := &v1beta1.AppArmorProfileFragment{}
+
+		err = json.Unmarshal(raw, f)
+
+		if err != nil {
+			return err
+		}
+
+		return v1beta1.ValidateAppArmorProfileFragment(f)
+	case meta.Kind == v1beta1.BindingKind && meta.APIVersion == v1beta1.SchemeGroupVersion.String():
+		b := &v1beta1.AppArmorProfileBinding{}
+
+		err = json.Unmarshal(raw, b)
+
+		if err != nil {
+			return err
+		}
+
+		return v1beta1.ValidateAppArmorProfileBinding(b)
+	case meta.Kind == v1alpha1.Kind && meta.APIVersion == v1beta1.SchemeGroupVersion.String():
+		p := &v1beta1.AppArmorProfile{}
+
+		err = json.Unmarshal(raw, p)
+
+		if err != nil {
+			return err
+		}
+
+		return v1beta1.ValidateAppArmorProfile(p)
+	case meta.Kind == v1alpha1.Kind && meta.APIVersion == v1alpha1.SchemeGroupVersion.String():
+		in := &v1alpha1.AppArmorProfile{}
+
+		err = json.Unmarshal(raw, in)
+
+		if err != nil {
+			return err
+		}
+
+		p := &v1beta1.AppArmorProfile{}
+
+		err = v1beta1.ConvertFromV1alpha1(in, p)
+
+		if err != nil {
+			return err
+		}
+
+		return v1beta1.ValidateV1alpha1AppArmorProfile(p)
+	}
+
+	return fmt.Errorf("unsupported object %s %s", meta.APIVersion, meta.Kind)
+}
@@ -0,0 +1,247 @@
+package workload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// podTemplatePaths are the paths of the pod templates of the workload kinds, empty for the pod itself
+var podTemplatePaths = map[string][]string{
+	"Pod":                   {},
+	"PodTemplate":           {"template"},
+	"Deployment":            {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// Migration is the result of rewriting a manifest
+type Migration struct {
+	// Migrated is the number of annotations replaced by fields
+	Migrated int
+	// Warnings are the annotations which were left unchanged and why
+	Warnings []string
+}
+
+// MigrateManifest rewrites the AppArmor annotations of the pods and pod templates of a YAML manifest to the
+// securityContext.appArmorProfile fields of the containers. The key order is kept but the comments are lost,
+// documents of other kinds are kept as they are.
+func MigrateManifest(data []byte) ([]byte, Migration, error) {
+	migration := Migration{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+
+	for i := 0; ; i++ {
+		doc := yaml.MapSlice{}
+
+		err := decoder.Decode(&doc)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, migration, err
+		}
+
+		if len(doc) == 0 {
+			continue
+		}
+
+		migrateObject(doc, &migration)
+
+		encoded, err := yaml.Marshal(doc)
+
+		if err != nil {
+			return nil, migration, err
+		}
+
+		if out.Len() > 0 {
+			out.WriteString("---\n")
+		}
+
+		out.Write(encoded)
+	}
+
+	return out.Bytes(), migration, nil
+}
+
+// migrateObject rewrites the pod or pod template of an object, the items of a list are rewritten one by one
+func migrateObject(obj yaml.MapSlice, migration *Migration) {
+	kind, _ := get(obj, "kind").(string)
+
+	if strings.HasSuffix(kind, "List") {
+		items, _ := get(obj, "items").([]interface{})
+
+		for _, item := range items {
+			if item, ok := item.(yaml.MapSlice); ok {
+				migrateObject(item, migration)
+			}
+		}
+
+		return
+	}
+
+	path, ok := podTemplatePaths[kind]
+
+	if !ok {
+		return
+	}
+
+	template := obj
+
+	for _, key := range path {
+		template, ok = get(template, key).(yaml.MapSlice)
+
+		if !ok {
+			return
+		}
+	}
+
+	name, _ := get(get(obj, "metadata"), "name").(string)
+
+	migratePod(template, fmt.Sprintf("%s %s", kind, name), migration)
+}
+
+// migratePod moves the AppArmor annotations of a pod or pod template to the security contexts of its containers
+func migratePod(pod yaml.MapSlice, object string, migration *Migration) {
+	metadata, ok := get(pod, "metadata").(yaml.MapSlice)
+
+	if !ok {
+		return
+	}
+
+	annotations, ok := get(metadata, "annotations").(yaml.MapSlice)
+
+	if !ok {
+		return
+	}
+
+	spec, _ := get(pod, "spec").(yaml.MapSlice)
+	kept := yaml.MapSlice{}
+
+	for _, item := range annotations {
+		key, _ := item.Key.(string)
+		value, _ := item.Value.(string)
+
+		if !strings.HasPrefix(key, AnnotationPrefix) {
+			kept = append(kept, item)
+			continue
+		}
+
+		name := strings.TrimPrefix(key, AnnotationPrefix)
+		warning := migrateContainer(spec, name, value)
+
+		if warning != "" {
+			migration.Warnings = append(migration.Warnings, fmt.Sprintf("%s, container %s: %s", object, name, warning))
+			kept = append(kept, item)
+			continue
+		}
+
+		migration.Migrated++
+	}
+
+	if len(kept) == len(annotations) {
+		return
+	}
+
+	if len(kept) == 0 {
+		set(&metadata, "annotations", nil)
+	} else {
+		set(&metadata, "annotations", kept)
+	}
+
+	set(&pod, "metadata", metadata)
+}
+
+// migrateContainer sets the profile of an annotation in the security context of the container, the reason
+// the annotation is kept is returned
+func migrateContainer(spec yaml.MapSlice, name, value string) string {
+	profile, ok := ParseAnnotation(value)
+
+	if !ok {
+		return fmt.Sprintf("unknown profile %q", value)
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := get(spec, field).([]interface{})
+
+		for i, c := range containers {
+			container, ok := c.(yaml.MapSlice)
+
+			if !ok || get(container, "name") != name {
+				continue
+			}
+
+			securityContext, _ := get(container, "securityContext").(yaml.MapSlice)
+
+			if existing, ok := get(securityContext, "appArmorProfile").(yaml.MapSlice); ok {
+				if get(existing, "type") != profile.Type || (get(existing, "localhostProfile") != nil && get(existing, "localhostProfile") != profile.LocalhostProfile) {
+					return "the securityContext.appArmorProfile field sets another profile"
+				}
+
+				return ""
+			}
+
+			field := yaml.MapSlice{{Key: "type", Value: profile.Type}}
+
+			if profile.LocalhostProfile != "" {
+				field = append(field, yaml.MapItem{Key: "localhostProfile", Value: profile.LocalhostProfile})
+			}
+
+			set(&securityContext, "appArmorProfile", field)
+			set(&container, "securityContext", securityContext)
+			containers[i] = container
+
+			return ""
+		}
+	}
+
+	return "no such container"
+}
+
+// get returns the value of a key of a mapping, nil if the node is not a mapping or doesn't have the key
+func get(node interface{}, key string) interface{} {
+	m, ok := node.(yaml.MapSlice)
+
+	if !ok {
+		return nil
+	}
+
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+
+	return nil
+}
+
+// set sets the value of a key of a mapping in place, the key is appended if it is missing and removed if
+// the value is nil
+func set(m *yaml.MapSlice, key string, value interface{}) {
+	for i, item := range *m {
+		if item.Key != key {
+			continue
+		}
+
+		if value == nil {
+			*m = append((*m)[:i], (*m)[i+1:]...)
+		} else {
+			(*m)[i].Value = value
+		}
+
+		return
+	}
+
+	if value != nil {
+		*m = append(*m, yaml.MapItem{Key: key, Value: value})
+	}
+}
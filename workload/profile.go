@@ -0,0 +1,195 @@
+// Package workload reads the AppArmor profiles of pods, set with the container.apparmor.security.beta.kubernetes.io
+// annotations or the securityContext.appArmorProfile fields of Kubernetes 1.30, and rewrites workload manifests
+// from the annotations to the fields
+package workload
+
+import (
+	"encoding/json"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationPrefix is the prefix of the annotations setting the profile of a container
+	AnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+	// ProfileTypeLocalhost is a profile loaded on the node
+	ProfileTypeLocalhost = "Localhost"
+	// ProfileTypeRuntimeDefault is the default profile of the container runtime
+	ProfileTypeRuntimeDefault = "RuntimeDefault"
+	// ProfileTypeUnconfined runs the container without profile
+	ProfileTypeUnconfined = "Unconfined"
+
+	// SourceAnnotation is a profile set with the annotation of the container
+	SourceAnnotation = "annotation"
+	// SourceField is a profile set with the securityContext.appArmorProfile field of the container
+	SourceField = "field"
+	// SourcePodField is a profile set with the securityContext.appArmorProfile field of the pod
+	SourcePodField = "pod field"
+
+	localhostPrefix = "localhost/"
+	runtimeDefault  = "runtime/default"
+	unconfined      = "unconfined"
+)
+
+// Profile is the value of the securityContext.appArmorProfile field
+type Profile struct {
+	Type             string `json:"type"`
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// ParseAnnotation returns the profile an annotation value sets, false if the value is unknown
+func ParseAnnotation(value string) (Profile, bool) {
+	switch {
+	case strings.HasPrefix(value, localhostPrefix) && len(value) > len(localhostPrefix):
+		return Profile{Type: ProfileTypeLocalhost, LocalhostProfile: strings.TrimPrefix(value, localhostPrefix)}, true
+	case value == runtimeDefault:
+		return Profile{Type: ProfileTypeRuntimeDefault}, true
+	case value == unconfined:
+		return Profile{Type: ProfileTypeUnconfined}, true
+	}
+
+	return Profile{}, false
+}
+
+// Annotation returns the annotation value setting the profile
+func (p Profile) Annotation() string {
+	switch p.Type {
+	case ProfileTypeLocalhost:
+		return localhostPrefix + p.LocalhostProfile
+	case ProfileTypeRuntimeDefault:
+		return runtimeDefault
+	}
+
+	return unconfined
+}
+
+// Pod has the fields of a pod or a pod template setting AppArmor profiles, securityContext.appArmorProfile
+// is not part of the API types of this Kubernetes version
+type Pod struct {
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     PodSpec           `json:"spec"`
+	Status   PodStatus         `json:"status,omitempty"`
+}
+
+// PodStatus has the phase of a pod, empty for a pod template
+type PodStatus struct {
+	Phase string `json:"phase,omitempty"`
+}
+
+// PodSpec has the fields of a pod spec setting AppArmor profiles
+type PodSpec struct {
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+	InitContainers  []Container      `json:"initContainers,omitempty"`
+	Containers      []Container      `json:"containers,omitempty"`
+}
+
+// Container has the fields of a container setting AppArmor profiles
+type Container struct {
+	Name            string           `json:"name"`
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+}
+
+// SecurityContext has the AppArmor profile of a pod or container security context
+type SecurityContext struct {
+	AppArmorProfile *Profile `json:"appArmorProfile,omitempty"`
+}
+
+// ContainerProfile is the profile a container runs with
+type ContainerProfile struct {
+	Container string
+	Init      bool
+	Profile   Profile
+	// Source tells how the profile is set
+	Source string
+}
+
+// ParsePod returns the profile fields of a pod object
+func ParsePod(raw []byte) (*Pod, error) {
+	pod := &Pod{}
+
+	err := json.Unmarshal(raw, pod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pod, nil
+}
+
+// Name returns the name of the pod, the generate name prefix if it is not set yet
+func (p *Pod) Name() string {
+	if p.Metadata.Name == "" {
+		return p.Metadata.GenerateName
+	}
+
+	return p.Metadata.Name
+}
+
+// Terminated checks whether all the containers of a pod have terminated, its profiles are not used anymore
+func (p *Pod) Terminated() bool {
+	return p.Status.Phase == "Succeeded" || p.Status.Phase == "Failed"
+}
+
+// Profiles returns the profiles of the containers which have one. The field of a container takes precedence
+// over its annotation, which takes precedence over the field of the pod.
+func (p *Pod) Profiles() []ContainerProfile {
+	profiles := []ContainerProfile{}
+
+	for _, list := range []struct {
+		init       bool
+		containers []Container
+	}{
+		{true, p.Spec.InitContainers},
+		{false, p.Spec.Containers},
+	} {
+		for _, c := range list.containers {
+			profile, source, ok := p.containerProfile(c)
+
+			if ok {
+				profiles = append(profiles, ContainerProfile{Container: c.Name, Init: list.init, Profile: profile, Source: source})
+			}
+		}
+	}
+
+	return profiles
+}
+
+// HasProfile checks whether a profile is set for a container, even an invalid one
+func (p *Pod) HasProfile(c Container) bool {
+	_, _, ok := p.containerProfile(c)
+	_, annotated := p.Metadata.Annotations[AnnotationPrefix+c.Name]
+
+	return ok || annotated
+}
+
+func (p *Pod) containerProfile(c Container) (Profile, string, bool) {
+	if c.SecurityContext != nil && c.SecurityContext.AppArmorProfile != nil {
+		return *c.SecurityContext.AppArmorProfile, SourceField, true
+	}
+
+	if value, ok := p.Metadata.Annotations[AnnotationPrefix+c.Name]; ok {
+		profile, ok := ParseAnnotation(value)
+		return profile, SourceAnnotation, ok
+	}
+
+	if p.Spec.SecurityContext != nil && p.Spec.SecurityContext.AppArmorProfile != nil {
+		return *p.Spec.SecurityContext.AppArmorProfile, SourcePodField, true
+	}
+
+	return Profile{}, "", false
+}
+
+// LocalhostProfiles returns the containers of a pod indexed by the localhost profile they run with
+func (p *Pod) LocalhostProfiles() map[string][]string {
+	profiles := map[string][]string{}
+
+	for _, c := range p.Profiles() {
+		if c.Profile.Type == ProfileTypeLocalhost {
+			profiles[c.Profile.LocalhostProfile] = append(profiles[c.Profile.LocalhostProfile], c.Container)
+		}
+	}
+
+	return profiles
+}